@@ -0,0 +1,229 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAdminBody is the maximum size of a request body read by AdminHandler.
+const maxAdminBody = 64 << 10
+
+type adminSetting struct {
+	get func(conf *config) bool
+	set func(conf *config, on bool)
+}
+
+func flagSetting(flag uint32) adminSetting {
+	return adminSetting{
+		get: func(conf *config) bool { return conf.logflags&flag != 0 },
+		set: func(conf *config, on bool) { conf.setFlags(flag, on) },
+	}
+}
+
+// gAdminSettings are the settings AdminHandler reports and changes.
+var gAdminSettings = map[string]adminSetting{
+	"enabled": {
+		get: func(conf *config) bool { return conf.enabled },
+		set: func(conf *config, on bool) { conf.enabled = on },
+	},
+	"trace":   flagSetting(flagLogTrace),
+	"debug":   flagSetting(flagLogDebug),
+	"through": flagSetting(flagLogThrough),
+	"console": flagSetting(flagLogToConsole),
+}
+
+// adminRestore is a setting changed with a TTL, value is restored at at.
+type adminRestore struct {
+	value bool
+	at    time.Time
+	stop  func() bool
+}
+
+var gAdmin = struct {
+	lock     sync.Mutex
+	restores map[string]*adminRestore
+}{restores: map[string]*adminRestore{}}
+
+// AdminHandler returns an http.Handler to inspect and change the log settings
+// at runtime. GET reports the settings as JSON, with the levels written down
+// and the settings to be restored:
+//
+//	{"console":false,"debug":true,"enabled":true,
+//	 "levels":["info","warn","error","update","panic","abort","query","debug"],
+//	 "restore":{"debug":{"value":false,"at":"2022-08-01T10:10:00Z"}},
+//	 "through":true,"trace":false}
+//
+// PUT and POST change the settings given in a JSON object and report them as
+// GET does. With "ttl", the changed settings are restored to their previous
+// values after the duration, on the clock set with SetClock if it is a
+// TimerClock, e.g. debug logs for 10 minutes:
+//
+//	curl -X PUT -d '{"debug":true,"ttl":"10m"}' http://localhost:6060/debug/log
+//
+// A change without "ttl" cancels the pending restore of the settings it
+// changes. The handler must only be reachable by operators.
+func AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			changes, ttl, err := parseAdminChanges(http.MaxBytesReader(w, req.Body, maxAdminBody))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if len(changes) > 0 {
+				applyAdminChanges(changes, ttl)
+				fields := Fields{"remote": req.RemoteAddr}
+				if ttl > 0 {
+					fields["ttl"] = ttl
+				}
+				logDepth(logLevelInfo, 0, fields, "Log settings changed: %s", []interface{}{formatAdminChanges(changes)})
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(adminReport())
+	})
+}
+
+// parseAdminChanges reads a JSON object of settings and an optional ttl.
+func parseAdminChanges(r io.Reader) (map[string]bool, time.Duration, error) {
+	var body map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return nil, 0, fmt.Errorf("invalid JSON object: %v", err)
+	}
+
+	var ttl time.Duration
+	changes := map[string]bool{}
+	for name, v := range body {
+		if name == "ttl" {
+			s, ok := v.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("ttl must be a duration string, e.g. \"10m\"")
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return nil, 0, fmt.Errorf("invalid ttl %q", s)
+			}
+			ttl = d
+			continue
+		}
+		if _, ok := gAdminSettings[name]; !ok {
+			return nil, 0, fmt.Errorf("unknown setting %q", name)
+		}
+		on, ok := v.(bool)
+		if !ok {
+			return nil, 0, fmt.Errorf("setting %q must be true or false", name)
+		}
+		changes[name] = on
+	}
+	return changes, ttl, nil
+}
+
+// applyAdminChanges changes the settings at once. With ttl > 0, the value a
+// setting had before its first pending change is restored after ttl.
+func applyAdminChanges(changes map[string]bool, ttl time.Duration) {
+	gAdmin.lock.Lock()
+	defer gAdmin.lock.Unlock()
+
+	prev := updateConfPrev(func(conf *config) {
+		for name, on := range changes {
+			gAdminSettings[name].set(conf, on)
+		}
+	})
+
+	for name, on := range changes {
+		value := gAdminSettings[name].get(prev)
+		if r := gAdmin.restores[name]; r != nil {
+			r.stop()
+			value = r.value
+			delete(gAdmin.restores, name)
+		}
+		if ttl <= 0 || value == on {
+			continue
+		}
+
+		name := name
+		r := &adminRestore{value: value, at: prev.now().Add(ttl)}
+		r.stop = prev.afterFunc(ttl, func() { restoreAdminSetting(name, r) })
+		gAdmin.restores[name] = r
+	}
+}
+
+// updateConfPrev works like updateConf, but returns the configuration before
+// the update.
+func updateConfPrev(fn func(conf *config)) *config {
+	var prev *config
+	updateConf(func(conf *config) {
+		prev = loadConf()
+		fn(conf)
+	})
+	return prev
+}
+
+func restoreAdminSetting(name string, r *adminRestore) {
+	gAdmin.lock.Lock()
+	if gAdmin.restores[name] != r {
+		// changed again meanwhile
+		gAdmin.lock.Unlock()
+		return
+	}
+	delete(gAdmin.restores, name)
+	updateConf(func(conf *config) { gAdminSettings[name].set(conf, r.value) })
+	gAdmin.lock.Unlock()
+
+	logDepth(logLevelInfo, 0, nil, "Log setting restored: %s", []interface{}{formatAdminChanges(map[string]bool{name: r.value})})
+}
+
+// formatAdminChanges formats the changes as sorted name=value pairs.
+func formatAdminChanges(changes map[string]bool) string {
+	pairs := make([]string, 0, len(changes))
+	for name, on := range changes {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, on))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+type adminRestoreReport struct {
+	Value bool      `json:"value"`
+	At    time.Time `json:"at"`
+}
+
+// adminReport returns the settings reported by AdminHandler.
+func adminReport() map[string]interface{} {
+	gAdmin.lock.Lock()
+	defer gAdmin.lock.Unlock()
+
+	conf := loadConf()
+	report := map[string]interface{}{}
+	for name, s := range gAdminSettings {
+		report[name] = s.get(conf)
+	}
+
+	levels := []string{}
+	for l := Level(0); l.valid(); l++ {
+		if conf.isEnabled() && conf.levelEnabled(int(l)) {
+			levels = append(levels, l.String())
+		}
+	}
+	report["levels"] = levels
+
+	restore := map[string]adminRestoreReport{}
+	for name, r := range gAdmin.restores {
+		restore[name] = adminRestoreReport{Value: r.value, At: r.at}
+	}
+	report["restore"] = restore
+	return report
+}
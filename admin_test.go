@@ -0,0 +1,124 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func adminRequest(t *testing.T, method, body string) (int, map[string]interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	AdminHandler().ServeHTTP(rec, httptest.NewRequest(method, "/debug/log", strings.NewReader(body)))
+	var report map[string]interface{}
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, report
+}
+
+func TestAdminHandler_ReportsAndChangesSettings(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+
+	code, report := adminRequest(t, http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if report["debug"] != false || report["through"] != true || report["enabled"] != true {
+		t.Fatalf("unexpected report: %v", report)
+	}
+	if levels := report["levels"].([]interface{}); len(levels) != logLevelMax-2 || levels[0] != "info" {
+		t.Fatalf("unexpected levels: %v", levels)
+	}
+
+	code, report = adminRequest(t, http.MethodPut, `{"debug":true,"trace":true}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if report["debug"] != true || report["trace"] != true || len(report["levels"].([]interface{})) != logLevelMax {
+		t.Fatalf("unexpected report: %v", report)
+	}
+	if !loadConf().logDebug() || !loadConf().logTrace() {
+		t.Fatal("expected debug and trace logs turned on")
+	}
+	if out := readLogFile(t, dir, "x.info"); !strings.Contains(out, "Log settings changed: debug=true trace=true") {
+		t.Fatalf("expected the change in info log, got:\n%s", out)
+	}
+}
+
+func TestAdminHandler_RestoresAfterTTL(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	clock := &fakeClock{t: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+
+	_, report := adminRequest(t, http.MethodPost, `{"debug":true,"ttl":"10m"}`)
+	if r, ok := report["restore"].(map[string]interface{})["debug"].(map[string]interface{}); !ok || r["value"] != false || r["at"] != "2022-08-01T10:10:00Z" {
+		t.Fatalf("expected a pending restore of debug, got %v", report)
+	}
+	// a second temporary change keeps the value to restore
+	clock.Add(5 * time.Minute)
+	adminRequest(t, http.MethodPost, `{"debug":true,"ttl":"10m"}`)
+	clock.Add(9 * time.Minute)
+	if !loadConf().logDebug() {
+		t.Fatal("expected debug logs turned on until the second ttl")
+	}
+
+	clock.Add(time.Minute)
+	if loadConf().logDebug() {
+		t.Fatal("debug logs were not turned off after the ttl")
+	}
+	if _, report := adminRequest(t, http.MethodGet, ""); len(report["restore"].(map[string]interface{})) != 0 {
+		t.Fatalf("expected no pending restore, got %v", report)
+	}
+}
+
+func TestAdminHandler_ChangeWithoutTTLCancelsRestore(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	clock := &fakeClock{t: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+
+	adminRequest(t, http.MethodPut, `{"console":true,"ttl":"10ms"}`)
+	adminRequest(t, http.MethodPut, `{"console":false}`)
+	adminRequest(t, http.MethodPut, `{"debug":true}`)
+	clock.Add(time.Second)
+
+	if loadConf().logToConsole() || !loadConf().logDebug() {
+		t.Fatal("expected the settings without ttl to be kept")
+	}
+}
+
+func TestAdminHandler_RejectsInvalidRequests(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+
+	for _, body := range []string{`not json`, `{"verbose":true}`, `{"debug":"yes"}`, `{"ttl":"soon"}`, `{"ttl":60}`} {
+		if code, _ := adminRequest(t, http.MethodPut, body); code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for %s, got %d", body, code)
+		}
+	}
+	if code, _ := adminRequest(t, http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", code)
+	}
+	if loadConf().logDebug() {
+		t.Fatal("expected settings unchanged")
+	}
+}
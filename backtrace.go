@@ -0,0 +1,110 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// backtraceAt holds the locations set with SetBacktraceAt and the decision
+// cached per call site. It is never modified once stored in a config, but
+// its cache.
+type backtraceAt struct {
+	spec      string
+	locations map[string]bool // file.go:123
+	cache     sync.Map        // pc uintptr -> bool
+}
+
+// SetBacktraceAt sets the locations, as a comma-separated list of file:line,
+// e.g. handler.go:123, a log written from appends the stack of the goroutine
+// writing it, as the stack field. The file is matched by its base name. An
+// empty spec clears the locations.
+func SetBacktraceAt(spec string) error {
+	bt, err := parseBacktraceAt(spec)
+	if err != nil {
+		return err
+	}
+	updateConf(func(conf *config) { conf.backtraceAt = bt })
+	return nil
+}
+
+// GetBacktraceAt returns the spec set with SetBacktraceAt.
+func GetBacktraceAt() string {
+	if bt := loadConf().backtraceAt; bt != nil {
+		return bt.spec
+	}
+	return ""
+}
+
+func parseBacktraceAt(spec string) (*backtraceAt, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	bt := &backtraceAt{spec: spec, locations: map[string]bool{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		colon := strings.LastIndex(item, ":")
+		if colon <= 0 {
+			return nil, fmt.Errorf("invalid backtrace location %q: expected file:line", item)
+		}
+		line, err := strconv.Atoi(item[colon+1:])
+		if err != nil || line <= 0 {
+			return nil, fmt.Errorf("invalid backtrace location %q: expected file:line", item)
+		}
+		bt.locations[path.Base(item[:colon])+":"+strconv.Itoa(line)] = true
+	}
+	return bt, nil
+}
+
+// withBacktrace returns fields with the stack of the goroutine added, if the
+// caller depth frames above the function calling withBacktrace is one of the
+// locations of SetBacktraceAt. Otherwise fields are returned as is.
+func (conf *config) withBacktrace(depth int, fields Fields) Fields {
+	if conf.backtraceAt == nil {
+		return fields
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(3+depth, pcs[:]) == 0 || !conf.backtraceAt.at(pcs[0]) {
+		return fields
+	}
+
+	key := "stack"
+	if _, ok := fields[key]; ok {
+		key = "backtrace"
+	}
+	withStack := make(Fields, len(fields)+1)
+	for k, v := range fields {
+		withStack[k] = v
+	}
+	withStack[key] = goroutineStack()
+	return withStack
+}
+
+// at reports whether pc is at one of the locations, cached per pc.
+func (bt *backtraceAt) at(pc uintptr) bool {
+	if at, ok := bt.cache.Load(pc); ok {
+		return at.(bool)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	at := bt.locations[path.Base(frame.File)+":"+strconv.Itoa(frame.Line)]
+	bt.cache.Store(pc, at)
+	return at
+}
+
+// goroutineStack returns the stack of the calling goroutine.
+func goroutineStack() string {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// warnMysteriously writes a warning and returns the line it is written from.
+func warnMysteriously(msg string) int {
+	_, _, line, _ := runtime.Caller(0)
+	Warn("%s", msg)
+	return line + 1
+}
+
+func TestSetBacktraceAt(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+	obs := NewObserver()
+	defer AddSink(obs)()
+
+	line := warnMysteriously("before")
+	spec := fmt.Sprintf("other.go:1, backtrace_test.go:%d", line)
+	if err := SetBacktraceAt(spec); err != nil {
+		t.Fatalf("SetBacktraceAt failed: %v", err)
+	}
+	if GetBacktraceAt() != spec {
+		t.Fatalf("unexpected spec %q", GetBacktraceAt())
+	}
+	warnMysteriously("located")
+	Warn("elsewhere")
+
+	entries := obs.All()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		_, ok := e.Fields["stack"]
+		if ok != (e.Message == "located") {
+			t.Fatalf("entry %d %q: unexpected stack field %v", i, e.Message, ok)
+		}
+	}
+
+	out := readLogFile(t, dir, "x.warn")
+	i := strings.Index(out, "located")
+	if i < 0 || !strings.Contains(out[i:], "goroutine ") || !strings.Contains(out[i:], "go-log.warnMysteriously") {
+		t.Fatalf("expected the stack after the located log, got:\n%s", out)
+	}
+	if strings.Contains(out[:i], "goroutine ") {
+		t.Fatalf("expected no stack before the located log, got:\n%s", out)
+	}
+}
+
+func TestSetBacktraceAt_KeepsStackField(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+	obs := NewObserver()
+
+	ctx := ContextWithFields(ContextWithLogger(context.Background(), NewSinkLogger(obs)), Fields{"stack": "given"})
+	_, _, line, _ := runtime.Caller(0)
+	if err := SetBacktraceAt(fmt.Sprintf("backtrace_test.go:%d", line+4)); err != nil {
+		t.Fatalf("SetBacktraceAt failed: %v", err)
+	}
+	WarnContext(ctx, "with stack")
+
+	e := obs.All()[0]
+	if e.Fields["stack"] != "given" || !strings.Contains(fmt.Sprint(e.Fields["backtrace"]), "goroutine ") {
+		t.Fatalf("unexpected fields %v", e.Fields)
+	}
+}
+
+func TestSetBacktraceAt_Invalid(t *testing.T) {
+	for _, spec := range []string{"handler.go", "handler.go:x", ":12", "handler.go:0"} {
+		if err := SetBacktraceAt(spec); err == nil {
+			t.Fatalf("expected an error for %q", spec)
+		}
+	}
+}
@@ -12,7 +12,7 @@ const digits = "0123456789"
 // buffer holds a byte Buffer for reuse. The zero value is ready for use.
 type buffer struct {
 	bytes.Buffer
-	tmp  [32]byte // temporary byte array for creating headers.
+	tmp  [64]byte // temporary byte array for creating headers.
 	next *buffer
 }
 
@@ -23,6 +23,14 @@ func (buf *buffer) twoDigits(i, d int) {
 	buf.tmp[i] = digits[d%10]
 }
 
+// nDigits formats an n-digit integer at buf.tmp[i], padding with zeros.
+func (buf *buffer) nDigits(n, i, d int) {
+	for j := n - 1; j >= 0; j-- {
+		buf.tmp[i+j] = digits[d%10]
+		d /= 10
+	}
+}
+
 // someDigits formats a zero-prefixed variable-width integer at buf.tmp[i].
 func (buf *buffer) someDigits(i, d int) int {
 	// Print into the top, then copy down. We know there's space for at least
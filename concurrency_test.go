@@ -2,6 +2,8 @@ package log
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -23,6 +25,9 @@ func TestConcurrentLogging(t *testing.T) {
 	// Reduce console noise during test
 	SetLogToConsole(false)
 
+	obs := NewObserver()
+	defer AddSink(obs)()
+
 	var wg sync.WaitGroup
 	nGoroutines := 50
 	perG := 200
@@ -43,9 +48,94 @@ func TestConcurrentLogging(t *testing.T) {
 
 	wg.Wait()
 
+	// Debug is muted, every other call must have been logged exactly once.
+	if n, want := obs.Len(), nGoroutines*perG*4; n != want {
+		t.Fatalf("expected %d entries, got %d", want, n)
+	}
+	if n := obs.FilterLevel(LevelDebug).Len(); n != 0 {
+		t.Fatalf("expected no debug entries, got %d", n)
+	}
+
 	// Disable further logging to avoid race with test cleanup if any.
 	SetLogDisable()
 
 	// Attempt best-effort cleanup
 	_ = os.RemoveAll(dir)
 }
+
+// TestConcurrentReconfiguration changes every setting while logging.
+// Run with -race to detect data races.
+func TestConcurrentReconfiguration(t *testing.T) {
+	ResetForTests()
+	dirs := []string{t.TempDir(), t.TempDir()}
+	if err := Init(dirs[0], 1, true); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				Info("gor=%d info", id)
+				Error("gor=%d error", id)
+				Debug("gor=%d debug", id)
+			}
+		}(i)
+	}
+
+	for i := 0; i < 50; i++ {
+		on := i%2 == 0
+		SetLogTrace(on)
+		SetLogDebug(on)
+		SetLogThrough(on)
+		SetLogFunctionName(on)
+		SetLogUserName("user")
+		SetMaxDays(i)
+		SetMaxFileSizeBytes(int64(i * 100))
+		SetLogFormat(Format(i % 3))
+		SetFilenamePrefix("x", "x")
+		if err := SetLogPath(dirs[i%2]); err != nil {
+			t.Fatalf("SetLogPath failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestSetLogPath(t *testing.T) {
+	ResetForTests()
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	if err := Init(dir1, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+
+	Info("first")
+	if err := SetLogPath(dir2); err != nil {
+		t.Fatalf("SetLogPath failed: %v", err)
+	}
+	if got := GetLogPath(); got != dir2+"/" {
+		t.Fatalf("expected log path %q, got %q", dir2+"/", got)
+	}
+	Info("second")
+
+	if got := readLogFile(t, dir1, "x.info"); !strings.Contains(got, "first") || strings.Contains(got, "second") {
+		t.Fatalf("unexpected logfile in the first dir: %q", got)
+	}
+	if got := readLogFile(t, dir2, "x.info"); !strings.Contains(got, "second") || strings.Contains(got, "first") {
+		t.Fatalf("unexpected logfile in the second dir: %q", got)
+	}
+	if _, err := os.Lstat(filepath.Join(dir2, "x.warn")); !os.IsNotExist(err) {
+		t.Fatalf("expected no warn symlink before a warn log, got %v", err)
+	}
+}
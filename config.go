@@ -4,23 +4,85 @@ import (
 	"os/user"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// logger configuration
+// logger configuration. A stored config is never modified, settings are
+// changed by storing a modified copy with updateConf, so the logs read a
+// consistent snapshot without locking.
 type config struct {
-	logPath    string
-	pathPrefix string
-	logflags   uint32
-	maxdays    int // limit log files by days, zero unlimited
-	purgeLock  sync.Mutex
-	enabled    bool
+	logPath        string
+	pathPrefix     string
+	filenamePrefix string // as set, with the placeholders
+	symlinkPrefix  string // as set, with the placeholders
+	linkPrefix     string // symlinkPrefix resolved
+	logflags       uint32
+	maxdays        int           // limit log files by days, zero unlimited
+	maxFileSize    int64         // rotate when a logfile exceeds it, zero unlimited
+	checkInterval  time.Duration // check the current files every interval, zero never
+	vmodule        *vmodule
+	backtraceAt    *backtraceAt
+	enabled        bool
+	format         Format
+	clock          Clock
+	fs             FS
+	userName       string
+	hostName       string
+	levels         []levelDef
+	loggers        []*logger    // per level
+	through        [][]int      // per level, the levels written through to
+	all            *logger      // the combined logfile, see SetAllFile
+	console        *consoleSink // see SetConsoleOptions
+	timeOpts       TimeOptions
 }
 
-var gConf = config{
-	logPath:  "./log/",
-	logflags: flagLogFilenameLineNum | flagLogThrough,
-	maxdays:  30,
-	enabled:  true,
+var (
+	gConfValue atomic.Value // *config
+	gConfLock  sync.Mutex   // serialises updateConf
+	gPurgeLock sync.Mutex
+)
+
+// defaultConfig returns the configuration used on package init.
+func defaultConfig() *config {
+	conf := &config{
+		logPath:       "./log/",
+		logflags:      flagLogFilenameLineNum | flagLogThrough,
+		maxdays:       30,
+		checkInterval: DefFileCheckInterval,
+		enabled:       true,
+		console:       newConsoleSink(DefaultConsoleOptions()),
+		levels:        append([]levelDef(nil), gDefaultLevels[:]...),
+	}
+	for i := range conf.levels {
+		conf.loggers = append(conf.loggers, &logger{level: i})
+		conf.through = append(conf.through, conf.defaultThrough(i))
+	}
+	conf.all = &logger{level: logLevelAll}
+	conf.setFilenamePrefix(DefFilenamePrefix, DefSymlinkPrefix)
+	return conf
+}
+
+// loadConf returns the current configuration, which must not be modified.
+func loadConf() *config {
+	return gConfValue.Load().(*config)
+}
+
+// updateConf stores a copy of the current configuration modified by fn and
+// returns it.
+func updateConf(fn func(conf *config)) *config {
+	gConfLock.Lock()
+	defer gConfLock.Unlock()
+
+	conf := *loadConf()
+	fn(&conf)
+	gConfValue.Store(&conf)
+	return &conf
+}
+
+// setFlags turns the given flag on or off.
+func setFlags(flag uint32, on bool) {
+	updateConf(func(conf *config) { conf.setFlags(flag, on) })
 }
 
 func (conf *config) setFlags(flag uint32, on bool) {
@@ -55,11 +117,126 @@ func (conf *config) logToConsole() bool {
 	return (conf.logflags & flagLogToConsole) != 0
 }
 
+func (conf *config) logAll() bool {
+	return (conf.logflags & flagLogAll) != 0
+}
+
+// files returns the loggers of the levels and of the combined logfile.
+func (conf *config) files() []*logger {
+	return append(conf.loggers[:len(conf.loggers):len(conf.loggers)], conf.all)
+}
+
+// fileName returns the name of the logfiles of the level, used in the
+// logfile and symlink names.
+func (conf *config) fileName(logLevel int) string {
+	if logLevel == logLevelAll {
+		return allFileName
+	}
+	return conf.levels[logLevel].name
+}
+
+// filePrefix returns the path prefix and the name of the logfiles of the
+// level.
+func (conf *config) filePrefix(logLevel int) string {
+	return conf.pathPrefix + conf.fileName(logLevel)
+}
+
+// symlink returns the path of the symlink to the logfile of the level.
+func (conf *config) symlink(logLevel int) string {
+	return conf.logPath + conf.linkPrefix + conf.fileName(logLevel)
+}
+
+// levelEnabled reports whether logs with the given level are written down,
+// trace and debug logs are muted unless turned on.
+func (conf *config) levelEnabled(logLevel int) bool {
+	switch logLevel {
+	case logLevelTrace:
+		return conf.logTrace()
+	case logLevelDebug:
+		return conf.logDebug()
+	}
+	return true
+}
+
+func (conf *config) stdLogTags() bool {
+	return (conf.logflags & flagStdLogTags) != 0
+}
+
+// now returns the time of the configured clock.
+func (conf *config) now() time.Time {
+	if conf.clock == nil {
+		return time.Now()
+	}
+	return conf.clock.Now()
+}
+
+// afterFunc calls f once d has elapsed on the configured clock.
+func (conf *config) afterFunc(d time.Duration, f func()) (stop func() bool) {
+	if c, ok := conf.clock.(TimerClock); ok {
+		return c.AfterFunc(d, f)
+	}
+	return time.AfterFunc(d, f).Stop
+}
+
+// ticker delivers the ticks of an interval of a clock on C.
+type ticker struct {
+	C    <-chan time.Time
+	Stop func()
+}
+
+// newTicker returns a ticker of the configured clock, with the ticks dropped
+// while the receiver is behind, as time.Ticker does.
+func (conf *config) newTicker(d time.Duration) *ticker {
+	c, ok := conf.clock.(TimerClock)
+	if !ok {
+		t := time.NewTicker(d)
+		return &ticker{C: t.C, Stop: t.Stop}
+	}
+
+	ch := make(chan time.Time, 1)
+	var lock sync.Mutex
+	var stop func() bool
+	stopped := false
+	var tick func()
+	tick = func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if stopped {
+			return
+		}
+		select {
+		case ch <- c.Now():
+		default:
+		}
+		stop = c.AfterFunc(d, tick)
+	}
+	lock.Lock()
+	stop = c.AfterFunc(d, tick)
+	lock.Unlock()
+	return &ticker{C: ch, Stop: func() {
+		lock.Lock()
+		stopped = true
+		stop()
+		lock.Unlock()
+	}}
+}
+
+// filesystem returns the configured filesystem.
+func (conf *config) filesystem() FS {
+	if conf.fs == nil {
+		return osFS{}
+	}
+	return conf.fs
+}
+
 func (conf *config) isEnabled() bool {
 	return conf.enabled
 }
 
 func (conf *config) setFilenamePrefix(filenamePrefix, symlinkPrefix string) {
+	conf.filenamePrefix = filenamePrefix
+	conf.symlinkPrefix = symlinkPrefix
+
 	username := "Unknown"
 	curUser, err := user.Current()
 	if err == nil {
@@ -70,33 +247,26 @@ func (conf *config) setFilenamePrefix(filenamePrefix, symlinkPrefix string) {
 	conf.pathPrefix = conf.logPath
 	if len(filenamePrefix) > 0 {
 		filenamePrefix = strings.Replace(filenamePrefix, "%P", gProgname, -1)
-		filenamePrefix = strings.Replace(filenamePrefix, "%H", infoHostName, -1)
+		filenamePrefix = strings.Replace(filenamePrefix, "%H", conf.hostName, -1)
 		filenamePrefix = strings.Replace(filenamePrefix, "%U", username, -1)
 		conf.pathPrefix = conf.pathPrefix + filenamePrefix + "."
 	}
 
 	if len(symlinkPrefix) > 0 {
 		symlinkPrefix = strings.Replace(symlinkPrefix, "%P", gProgname, -1)
-		symlinkPrefix = strings.Replace(symlinkPrefix, "%H", infoHostName, -1)
+		symlinkPrefix = strings.Replace(symlinkPrefix, "%H", conf.hostName, -1)
 		symlinkPrefix = strings.Replace(symlinkPrefix, "%U", username, -1)
 		symlinkPrefix += "."
 	}
-
-	isSymlink = map[string]bool{}
-	for i := 0; i != logLevelMax; i++ {
-		gLoggers[i].level = i
-		gSymlinks[i] = symlinkPrefix + gLogLevelNames[i]
-		isSymlink[gSymlinks[i]] = true
-		gFullSymlinks[i] = conf.logPath + gSymlinks[i]
-	}
+	conf.linkPrefix = symlinkPrefix
 }
 
 // SetMaxDays - change maxdays parameter
 func SetMaxDays(days int) {
-	gConf.maxdays = days
+	updateConf(func(conf *config) { conf.maxdays = days })
 }
 
 // GetMaxDays - change maxdays parameter
 func GetMaxDays() int {
-	return gConf.maxdays
+	return loadConf().maxdays
 }
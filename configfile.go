@@ -0,0 +1,741 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the
+// settings of a configuration file, e.g. GOLOG_DIR or GOLOG_LEVEL.
+const EnvPrefix = "GOLOG_"
+
+// FileConfig holds the settings read by LoadConfig. The nil settings are
+// left unchanged by Apply; WatchConfig sets the settings removed from the
+// file back to their defaults.
+//
+// A configuration file is a JSON object or a YAML document of `key: value`
+// lines, e.g.
+//
+//	# /etc/app/log.yaml
+//	dir: /var/log/app
+//	maxdays: 7
+//	level: debug
+//	format: json
+//	maxfilesize: 100MB
+//	sinks: [audit]
+//
+// The keys are dir, maxdays, level, trace, debug, through, console, funcname,
+// linenum, username, filenameprefix, symlinkprefix, format, maxfilesize,
+// checkinterval, copytruncate, sinks, vmodule, backtraceat, allfile,
+// timeformat, timeprecision and timezone. Each key can be overridden by an
+// environment variable named after it, e.g. GOLOG_MAXDAYS=3 or
+// GOLOG_SINKS=audit,metrics.
+type FileConfig struct {
+	Dir     string // log directory, empty for unchanged
+	MaxDays *int
+
+	// Level is trace, debug or info: trace turns on trace and debug logs,
+	// debug turns on debug logs only and info neither. Trace and Debug take
+	// precedence.
+	Level    *Level
+	Trace    *bool
+	Debug    *bool
+	Through  *bool
+	Console  *bool
+	FuncName *bool
+	LineNum  *bool
+	UserName *string
+
+	FilenamePrefix *string
+	SymlinkPrefix  *string
+	Format         *Format
+
+	MaxFileSize   *int64 // bytes, with an optional KB, MB or GB suffix in files
+	CheckInterval *time.Duration
+	CopyTruncate  *bool
+
+	// Sinks are the names of the sinks registered with RegisterSink to
+	// write to, the other registered sinks are removed.
+	Sinks []string
+
+	// VModule is the spec of SetVModule.
+	VModule *string
+	// BacktraceAt is the spec of SetBacktraceAt.
+	BacktraceAt *string
+	// AllFile writes the combined logfile, see SetAllFile.
+	AllFile *bool
+
+	// TimeFormat is time, date or rfc3339: the time of day only, with the
+	// date or as RFC 3339, see TimeOptions.
+	TimeFormat *string
+	// TimePrecision is the Precision of TimeOptions, given as s, ms, us or
+	// ns in files.
+	TimePrecision *int
+	// TimeZone is the Location of TimeOptions, given as a name of
+	// time.LoadLocation in files, e.g. UTC or Europe/Vilnius.
+	TimeZone *time.Location
+}
+
+// gConfigKeys are the keys of a configuration file.
+var gConfigKeys = []string{
+	"dir", "maxdays", "level", "trace", "debug", "through", "console", "funcname",
+	"linenum", "username", "filenameprefix", "symlinkprefix", "format",
+	"maxfilesize", "checkinterval", "copytruncate", "sinks", "vmodule",
+	"backtraceat", "allfile", "timeformat", "timeprecision", "timezone",
+}
+
+// LoadConfig reads the configuration file path, as JSON if its extension is
+// .json or it starts with `{`, as YAML otherwise, then applies the environment
+// variable overrides. With an empty path, only the environment variables are
+// read.
+func LoadConfig(path string) (*FileConfig, error) {
+	var data []byte
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return parseConfig(path, data)
+}
+
+func parseConfig(path string, data []byte) (*FileConfig, error) {
+	values := map[string]interface{}{}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0:
+	case strings.EqualFold(filepath.Ext(path), ".json") || trimmed[0] == '{':
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	default:
+		var err error
+		if values, err = parseYAML(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	fromEnv := map[string]bool{}
+	for _, key := range gConfigKeys {
+		if v, ok := os.LookupEnv(EnvPrefix + strings.ToUpper(key)); ok {
+			values[key] = v
+			fromEnv[key] = true
+		}
+	}
+
+	cfg := &FileConfig{}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := cfg.set(key, values[key]); err != nil {
+			if fromEnv[key] {
+				return nil, fmt.Errorf("%s%s: %v", EnvPrefix, strings.ToUpper(key), err)
+			}
+			return nil, fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// set sets the setting key from a JSON value or a string.
+func (cfg *FileConfig) set(key string, v interface{}) error {
+	var err error
+	switch key {
+	case "dir":
+		cfg.Dir, err = configString(v)
+	case "maxdays":
+		var n int64
+		n, err = configInt(v)
+		days := int(n)
+		cfg.MaxDays = &days
+	case "level":
+		var s string
+		if s, err = configString(v); err == nil {
+			var l Level
+			if l, err = ParseLevel(s); err == nil {
+				err = checkConfigLevel(l)
+			}
+			cfg.Level = &l
+		}
+	case "trace":
+		cfg.Trace, err = configBool(v)
+	case "debug":
+		cfg.Debug, err = configBool(v)
+	case "through":
+		cfg.Through, err = configBool(v)
+	case "console":
+		cfg.Console, err = configBool(v)
+	case "funcname":
+		cfg.FuncName, err = configBool(v)
+	case "linenum":
+		cfg.LineNum, err = configBool(v)
+	case "copytruncate":
+		cfg.CopyTruncate, err = configBool(v)
+	case "allfile":
+		cfg.AllFile, err = configBool(v)
+	case "username":
+		cfg.UserName, err = configStringPtr(v)
+	case "filenameprefix":
+		cfg.FilenamePrefix, err = configStringPtr(v)
+	case "symlinkprefix":
+		cfg.SymlinkPrefix, err = configStringPtr(v)
+	case "format":
+		var s string
+		if s, err = configString(v); err == nil {
+			var f Format
+			f, err = ParseFormat(s)
+			cfg.Format = &f
+		}
+	case "maxfilesize":
+		var n int64
+		n, err = configByteSize(v)
+		cfg.MaxFileSize = &n
+	case "checkinterval":
+		var s string
+		if s, err = configString(v); err == nil {
+			var d time.Duration
+			d, err = time.ParseDuration(s)
+			cfg.CheckInterval = &d
+		}
+	case "sinks":
+		cfg.Sinks, err = configList(v)
+	case "vmodule":
+		var s string
+		if s, err = configString(v); err == nil {
+			_, err = parseVModule(s)
+			cfg.VModule = &s
+		}
+	case "backtraceat":
+		var s string
+		if s, err = configString(v); err == nil {
+			_, err = parseBacktraceAt(s)
+			cfg.BacktraceAt = &s
+		}
+	case "timeformat":
+		var s string
+		if s, err = configString(v); err == nil {
+			s = strings.ToLower(s)
+			if s != "time" && s != "date" && s != "rfc3339" {
+				err = fmt.Errorf("expected time, date or rfc3339, got %q", s)
+			}
+			cfg.TimeFormat = &s
+		}
+	case "timeprecision":
+		var s string
+		if s, err = configString(v); err == nil {
+			var p int
+			p, err = parseTimePrecision(s)
+			cfg.TimePrecision = &p
+		}
+	case "timezone":
+		var s string
+		if s, err = configString(v); err == nil {
+			cfg.TimeZone, err = time.LoadLocation(s)
+		}
+	default:
+		return fmt.Errorf("unknown setting")
+	}
+	return err
+}
+
+func configString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("expected a string, got %v", v)
+}
+
+func configStringPtr(v interface{}) (*string, error) {
+	s, err := configString(v)
+	return &s, err
+}
+
+func configBool(v interface{}) (*bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return &v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", v)
+		}
+		return &b, nil
+	}
+	return nil, fmt.Errorf("expected true or false, got %v", v)
+}
+
+func configInt(v interface{}) (int64, error) {
+	s, err := configString(v)
+	if err != nil {
+		return 0, fmt.Errorf("expected an integer, got %v", v)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected an integer, got %q", s)
+	}
+	return n, nil
+}
+
+// configByteSize parses a size in bytes with an optional KB, MB or GB suffix.
+func configByteSize(v interface{}) (int64, error) {
+	s, err := configString(v)
+	if err != nil {
+		return 0, fmt.Errorf("expected a size, got %v", v)
+	}
+	unit := int64(1)
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(upper, u.suffix) {
+			unit = u.size
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size, e.g. 100MB, got %q", s)
+	}
+	return n * unit, nil
+}
+
+// configList accepts a list or a comma-separated string.
+func configList(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		list := []string{}
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		return list, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := configString(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expected a list, got %v", v)
+}
+
+// parseYAML parses the YAML subset of a configuration file: `key: value`
+// lines, lists as `[a, b]` or as `- item` lines under a key without a value,
+// quoted strings and comments. The scalars are returned as strings.
+func parseYAML(path string, data []byte) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	listKey := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(stripYAMLComment(line))
+		if line == "" || line == "---" {
+			continue
+		}
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", path, i+1, fmt.Sprintf(format, args...))
+		}
+
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			if listKey == "" {
+				return nil, fail("list item without a key")
+			}
+			item, err := yamlScalar(strings.TrimSpace(strings.TrimPrefix(line, "-")))
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			values[listKey] = append(values[listKey].([]interface{}), item)
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if colon <= 0 {
+			return nil, fail("expected `key: value`, got %q", line)
+		}
+		key := strings.TrimSpace(line[:colon])
+		value := strings.TrimSpace(line[colon+1:])
+		listKey = ""
+		switch {
+		case value == "":
+			values[key] = []interface{}{}
+			listKey = key
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			list := []interface{}{}
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				s, err := yamlScalar(item)
+				if err != nil {
+					return nil, fail("%v", err)
+				}
+				list = append(list, s)
+			}
+			values[key] = list
+		default:
+			s, err := yamlScalar(value)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			values[key] = s
+		}
+	}
+	return values, nil
+}
+
+// stripYAMLComment removes a comment starting with # outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func yamlScalar(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	}
+	return s, nil
+}
+
+// checkConfigLevel checks the level setting is one of the levels it can turn
+// on, the other levels are always logged.
+func checkConfigLevel(l Level) error {
+	switch l {
+	case LevelTrace, LevelDebug, LevelInfo:
+		return nil
+	}
+	return fmt.Errorf("invalid level %q: expected trace, debug or info", l)
+}
+
+var gConfigSinks = struct {
+	lock    sync.Mutex
+	named   map[string]Sink
+	removes map[string]func() // sinks added by Apply
+}{named: map[string]Sink{}, removes: map[string]func(){}}
+
+// RegisterSink registers s with the given name, so a configuration file can
+// write to it with `sinks: [name]`.
+func RegisterSink(name string, s Sink) {
+	gConfigSinks.lock.Lock()
+	gConfigSinks.named[name] = s
+	gConfigSinks.lock.Unlock()
+}
+
+// Apply applies the settings of cfg at once. When the log directory or the
+// filename prefixes change, the logfiles are reopened. Nothing is changed if
+// an error is returned.
+func (cfg *FileConfig) Apply() error {
+	gConfigSinks.lock.Lock()
+	defer gConfigSinks.lock.Unlock()
+
+	if cfg.Level != nil {
+		if err := checkConfigLevel(*cfg.Level); err != nil {
+			return fmt.Errorf("level: %v", err)
+		}
+	}
+	if cfg.Sinks != nil {
+		for _, name := range cfg.Sinks {
+			if _, ok := gConfigSinks.named[name]; !ok {
+				return fmt.Errorf("unknown sink %q", name)
+			}
+		}
+	}
+
+	var hostName string
+	if cfg.Dir != "" {
+		if err := loadConf().filesystem().MkdirAll(cfg.Dir, 0755); err != nil {
+			return err
+		}
+		var err error
+		if hostName, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+
+	var reopen bool
+	conf := updateConf(func(conf *config) {
+		prefix := conf.pathPrefix
+		cfg.apply(conf, hostName)
+		reopen = conf.pathPrefix != prefix
+	})
+	if reopen {
+		closeFiles()
+	} else if !conf.logAll() {
+		conf.all.close()
+	}
+
+	if cfg.Sinks != nil {
+		keep := map[string]bool{}
+		for _, name := range cfg.Sinks {
+			keep[name] = true
+			if gConfigSinks.removes[name] == nil {
+				gConfigSinks.removes[name] = AddSink(gConfigSinks.named[name])
+			}
+		}
+		for name, remove := range gConfigSinks.removes {
+			if !keep[name] {
+				remove()
+				delete(gConfigSinks.removes, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg *FileConfig) apply(conf *config, hostName string) {
+	if cfg.Dir != "" {
+		conf.logPath = cfg.Dir + "/"
+		conf.hostName = hostName
+	}
+	if cfg.MaxDays != nil {
+		conf.maxdays = *cfg.MaxDays
+	}
+	if cfg.Level != nil {
+		conf.setFlags(flagLogTrace, *cfg.Level == LevelTrace)
+		conf.setFlags(flagLogDebug, *cfg.Level == LevelTrace || *cfg.Level == LevelDebug)
+	}
+	for _, s := range []struct {
+		on   *bool
+		flag uint32
+	}{
+		{cfg.Trace, flagLogTrace},
+		{cfg.Debug, flagLogDebug},
+		{cfg.Through, flagLogThrough},
+		{cfg.Console, flagLogToConsole},
+		{cfg.FuncName, flagLogFuncName},
+		{cfg.LineNum, flagLogFilenameLineNum},
+		{cfg.CopyTruncate, flagCopyTruncate},
+		{cfg.AllFile, flagLogAll},
+	} {
+		if s.on != nil {
+			conf.setFlags(s.flag, *s.on)
+		}
+	}
+	if cfg.UserName != nil {
+		conf.userName = *cfg.UserName
+	}
+	if cfg.Format != nil {
+		conf.format = *cfg.Format
+	}
+	if cfg.MaxFileSize != nil {
+		conf.maxFileSize = *cfg.MaxFileSize
+	}
+	if cfg.CheckInterval != nil {
+		conf.checkInterval = *cfg.CheckInterval
+	}
+	if cfg.VModule != nil {
+		// checked by set
+		conf.vmodule, _ = parseVModule(*cfg.VModule)
+	}
+	if cfg.BacktraceAt != nil {
+		// checked by set
+		conf.backtraceAt, _ = parseBacktraceAt(*cfg.BacktraceAt)
+	}
+	if cfg.TimeFormat != nil {
+		conf.timeOpts.Date = *cfg.TimeFormat == "date"
+		conf.timeOpts.RFC3339 = *cfg.TimeFormat == "rfc3339"
+	}
+	if cfg.TimePrecision != nil {
+		conf.timeOpts.Precision = *cfg.TimePrecision
+	}
+	if cfg.TimeZone != nil {
+		conf.timeOpts.Location = cfg.TimeZone
+	}
+
+	filenamePrefix, symlinkPrefix := conf.filenamePrefix, conf.symlinkPrefix
+	if cfg.FilenamePrefix != nil {
+		filenamePrefix = *cfg.FilenamePrefix
+	}
+	if cfg.SymlinkPrefix != nil {
+		symlinkPrefix = *cfg.SymlinkPrefix
+	}
+	conf.setFilenamePrefix(filenamePrefix, symlinkPrefix)
+}
+
+// defaultFileConfig returns the settings of the configuration used on
+// package init, but the log directory.
+func defaultFileConfig() *FileConfig {
+	conf := defaultConfig()
+	flag := func(f uint32) *bool {
+		on := conf.logflags&f != 0
+		return &on
+	}
+	str := func(s string) *string { return &s }
+	timeFormat := "time"
+	return &FileConfig{
+		MaxDays:        &conf.maxdays,
+		Trace:          flag(flagLogTrace),
+		Debug:          flag(flagLogDebug),
+		Through:        flag(flagLogThrough),
+		Console:        flag(flagLogToConsole),
+		FuncName:       flag(flagLogFuncName),
+		LineNum:        flag(flagLogFilenameLineNum),
+		UserName:       str(conf.userName),
+		FilenamePrefix: str(conf.filenamePrefix),
+		SymlinkPrefix:  str(conf.symlinkPrefix),
+		Format:         &conf.format,
+		MaxFileSize:    &conf.maxFileSize,
+		CheckInterval:  &conf.checkInterval,
+		CopyTruncate:   flag(flagCopyTruncate),
+		Sinks:          []string{},
+		VModule:        str(""),
+		BacktraceAt:    str(""),
+		AllFile:        flag(flagLogAll),
+		TimeFormat:     &timeFormat,
+		TimePrecision:  &conf.timeOpts.Precision,
+		TimeZone:       time.Local,
+	}
+}
+
+// withRemovedReset returns a copy of cfg where the settings set by prev but
+// not by cfg are set back to their defaults.
+func (cfg *FileConfig) withRemovedReset(prev *FileConfig) *FileConfig {
+	def := defaultFileConfig()
+	reset := *cfg
+	rv, pv, dv := reflect.ValueOf(&reset).Elem(), reflect.ValueOf(prev).Elem(), reflect.ValueOf(def).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Field(i)
+		if (f.Kind() == reflect.Ptr || f.Kind() == reflect.Slice) && f.IsNil() && !pv.Field(i).IsNil() {
+			f.Set(dv.Field(i))
+		}
+	}
+	// level turns trace and debug logs on or off
+	if prev.Level != nil && cfg.Level == nil {
+		if reset.Trace == nil {
+			reset.Trace = def.Trace
+		}
+		if reset.Debug == nil {
+			reset.Debug = def.Debug
+		}
+	}
+	return &reset
+}
+
+// InitFromConfig loads the configuration file path with LoadConfig and
+// applies it.
+func InitFromConfig(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return cfg.Apply()
+}
+
+// WatchConfig loads and applies the configuration file path, then checks it
+// every interval and applies it again when its content changes. A setting
+// removed from the file is set back to its default, as on package init, but
+// the log directory, which is kept. Removing level turns trace and debug logs
+// off, unless trace or debug is set. A file failing to load is logged down
+// with error level and the settings are kept. The interval must be positive,
+// it is counted on the clock set with SetClock if it is a TimerClock.
+// It returns a function to stop watching.
+func WatchConfig(path string, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid config watch interval %v: must be positive", interval)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Apply(); err != nil {
+		return nil, err
+	}
+	applied := cfg
+
+	ticker := loadConf().newTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+
+		// failed is the last error logged down, logged once until it changes
+		failed := ""
+		report := func(err error) {
+			if err.Error() != failed {
+				failed = err.Error()
+				logDepth(logLevelError, 1, nil, "Failed to reload log config: %v", []interface{}{err})
+			}
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			cur, err := os.ReadFile(path)
+			if err != nil {
+				report(err)
+				continue
+			}
+			if bytes.Equal(cur, data) {
+				continue
+			}
+			data = cur
+
+			cfg, err := parseConfig(path, data)
+			if err == nil {
+				err = cfg.withRemovedReset(applied).Apply()
+			}
+			if err != nil {
+				report(err)
+				continue
+			}
+			applied = cfg
+			failed = ""
+			logDepth(logLevelInfo, 0, nil, "Reloaded log config %s", []interface{}{path})
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}, nil
+}
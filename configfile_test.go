@@ -0,0 +1,202 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return p
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	p := writeConfig(t, t.TempDir(), "log.yaml", `
+# comment
+dir: "/var/log/app" # trailing comment
+maxdays: 7
+level: debug
+through: false
+format: logfmt
+username: 'o''brien'
+maxfilesize: 10 MB
+checkinterval: 5s
+sinks:
+  - audit
+  - metrics
+`)
+	cfg, err := LoadConfig(p)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Dir != "/var/log/app" || *cfg.MaxDays != 7 || *cfg.Level != LevelDebug || *cfg.Through ||
+		*cfg.Format != FormatLogfmt || *cfg.UserName != "o'brien" || *cfg.MaxFileSize != 10<<20 ||
+		*cfg.CheckInterval != 5*time.Second || strings.Join(cfg.Sinks, ",") != "audit,metrics" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if cfg.Console != nil || cfg.Trace != nil {
+		t.Fatalf("expected the missing settings nil: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_JSONAndEnv(t *testing.T) {
+	p := writeConfig(t, t.TempDir(), "log.json", `{"maxdays": 7, "console": true, "sinks": ["audit"]}`)
+	t.Setenv("GOLOG_MAXDAYS", "3")
+	t.Setenv("GOLOG_SINKS", "a, b")
+
+	cfg, err := LoadConfig(p)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if *cfg.MaxDays != 3 || !*cfg.Console || strings.Join(cfg.Sinks, ",") != "a,b" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for content, want := range map[string]string{
+		"maxdays: 7\nverbose: true\n": "verbose: unknown setting",
+		"maxdays: 7\nnot a setting\n": "log.yaml:2:",
+		"trace: maybe\n":              "trace: expected true or false",
+		"format: xml\n":               "unknown log format",
+		"level: error\n":              "level: invalid level \"error\": expected trace, debug or info",
+		"- item\n":                    "list item without a key",
+	} {
+		_, err := LoadConfig(writeConfig(t, dir, "log.yaml", content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error %q for %q, got %v", want, content, err)
+		}
+	}
+
+	t.Setenv("GOLOG_MAXDAYS", "many")
+	if _, err := LoadConfig(""); err == nil || !strings.HasPrefix(err.Error(), "GOLOG_MAXDAYS:") {
+		t.Fatalf("expected an error naming the environment variable, got %v", err)
+	}
+	t.Setenv("GOLOG_MAXDAYS", "7")
+	t.Setenv("GOLOG_LEVEL", "warn")
+	if _, err := LoadConfig(""); err == nil || !strings.HasPrefix(err.Error(), "GOLOG_LEVEL: invalid level") {
+		t.Fatalf("expected an error naming the level variable, got %v", err)
+	}
+}
+
+func TestFileConfig_Apply(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+
+	audit := NewObserver()
+	RegisterSink("audit", audit)
+	dir := t.TempDir()
+	p := writeConfig(t, dir, "log.yaml", "dir: "+dir+"/logs\nlevel: trace\ndebug: false\nfilenameprefix: app\nsymlinkprefix: app\nsinks: [audit]\n")
+	if err := InitFromConfig(p); err != nil {
+		t.Fatalf("InitFromConfig failed: %v", err)
+	}
+	conf := loadConf()
+	if conf.logPath != dir+"/logs/" || !conf.logTrace() || conf.logDebug() {
+		t.Fatalf("unexpected settings: %+v", conf)
+	}
+
+	Info("applied")
+	if got := readLogFile(t, dir+"/logs", "app.info"); !strings.Contains(got, "applied") {
+		t.Fatalf("expected the log in the configured directory, got %q", got)
+	}
+	if audit.Len() != 1 {
+		t.Fatalf("expected the log in the configured sink, got %d", audit.Len())
+	}
+
+	bad := &FileConfig{Sinks: []string{"missing"}, Trace: new(bool)}
+	if err := bad.Apply(); err == nil {
+		t.Fatal("expected an error for an unknown sink")
+	}
+	if !loadConf().logTrace() {
+		t.Fatal("expected nothing changed by a failed Apply")
+	}
+	warn := LevelWarn
+	if err := (&FileConfig{Level: &warn}).Apply(); err == nil || !loadConf().logTrace() {
+		t.Fatalf("expected an error for a level it cannot turn on, got %v", err)
+	}
+
+	if err := (&FileConfig{Sinks: []string{}}).Apply(); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	Info("without sinks")
+	if audit.Len() != 1 {
+		t.Fatalf("expected the sink removed, got %d entries", audit.Len())
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	clock := &fakeClock{t: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	entries := make(entrySink, 16)
+	defer AddSink(entries)()
+
+	p := writeConfig(t, t.TempDir(), "log.json", `{"debug": false}`)
+	if _, err := WatchConfig(p, 0); err == nil {
+		t.Fatal("expected an error for a zero interval")
+	}
+	stop, err := WatchConfig(p, time.Second)
+	if err != nil {
+		t.Fatalf("WatchConfig failed: %v", err)
+	}
+	defer stop()
+
+	writeConfig(t, filepath.Dir(p), "log.json", `{"debug": tru`)
+	clock.Add(time.Second)
+	waitEntry(t, entries, "Failed to reload log config")
+	if loadConf().logDebug() {
+		t.Fatal("expected the settings kept on an invalid file")
+	}
+
+	writeConfig(t, filepath.Dir(p), "log.json", `{"debug": true}`)
+	clock.Add(time.Second)
+	waitEntry(t, entries, "Reloaded log config")
+	if !loadConf().logDebug() {
+		t.Fatal("expected debug logs turned on by the reloaded file")
+	}
+}
+
+func TestWatchConfig_RemovedKeys(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	clock := &fakeClock{t: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	entries := make(entrySink, 16)
+	defer AddSink(entries)()
+
+	p := writeConfig(t, t.TempDir(), "log.yaml", "level: trace\nvmodule: gorm=off\ntimezone: UTC\nmaxdays: 3\n")
+	stop, err := WatchConfig(p, time.Second)
+	if err != nil {
+		t.Fatalf("WatchConfig failed: %v", err)
+	}
+	defer stop()
+	if conf := loadConf(); !conf.logTrace() || !conf.logDebug() || GetVModule() != "gorm=off" || conf.timeOpts.Location != time.UTC {
+		t.Fatal("expected the settings of the file applied")
+	}
+
+	writeConfig(t, filepath.Dir(p), "log.yaml", "maxdays: 3\n")
+	clock.Add(time.Second)
+	waitEntry(t, entries, "Reloaded log config")
+	if conf := loadConf(); GetVModule() != "" || conf.logTrace() || conf.logDebug() || conf.timeOpts.Location != time.Local || conf.maxdays != 3 {
+		t.Fatalf("expected the removed settings reset, got vmodule=%q trace=%v debug=%v location=%v maxdays=%d",
+			GetVModule(), conf.logTrace(), conf.logDebug(), conf.timeOpts.Location, conf.maxdays)
+	}
+}
@@ -0,0 +1,258 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ColorMode sets whether the console output is colored.
+type ColorMode int
+
+// color modes
+const (
+	// ColorAuto colors the output written to a terminal, unless the NO_COLOR
+	// environment variable is set or TERM is dumb.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors the output wherever it is written.
+	ColorAlways
+	// ColorNever writes plain output.
+	ColorNever
+)
+
+// maxConsoleCallerWidth is the widest the caller column is padded to.
+const maxConsoleCallerWidth = 32
+
+// gConsoleColors are the default SGR parameters of the levels.
+var gConsoleColors = map[Level]string{
+	LevelTrace:  "90",
+	LevelDebug:  "36",
+	LevelQuery:  "34",
+	LevelInfo:   "32",
+	LevelUpdate: "35",
+	LevelWarn:   "33",
+	LevelError:  "31",
+	LevelPanic:  "1;31",
+	LevelAbort:  "1;97;41",
+}
+
+// gStartTime is the time the relative timestamps are counted from.
+var gStartTime = time.Now()
+
+// ConsoleOptions configures the console output of SetLogToConsole and the
+// sink returned by NewConsoleSink.
+type ConsoleOptions struct {
+	// MinLevel is the least severe level written, compared by Rank, so the
+	// console may be quieter than the logfiles. The zero value writes every
+	// level.
+	MinLevel Level
+	// Stderr are the levels written to Err, the others are written to Out.
+	Stderr []Level
+	// Color sets whether the level names and the fields are colored with
+	// ANSI escape sequences.
+	Color ColorMode
+	// Colors are the SGR parameters of the levels, e.g. "1;31" for bold red,
+	// overriding the defaults. A level without a color is not colored.
+	Colors map[Level]string
+	// RelativeTime writes the seconds elapsed since the program started
+	// instead of the time of day.
+	RelativeTime bool
+	// Out and Err are the writers of the output, os.Stdout and os.Stderr if
+	// nil.
+	Out io.Writer
+	Err io.Writer
+}
+
+// DefaultConsoleOptions returns the options used by SetLogToConsole: every
+// level is written to stdout, colored when it is a terminal.
+func DefaultConsoleOptions() ConsoleOptions {
+	return ConsoleOptions{Color: ColorAuto}
+}
+
+// SetConsoleOptions sets the options of the console output turned on with
+// SetLogToConsole. The console output is written in columns for humans with
+// FormatText; with the other formats, the lines of the logfiles are written
+// as they are, to Out or Err and from MinLevel on.
+func SetConsoleOptions(opts ConsoleOptions) {
+	s := newConsoleSink(opts)
+	updateConf(func(conf *config) { conf.console = s })
+}
+
+// NewConsoleSink returns a sink writing the entries in aligned columns for
+// humans:
+//
+//	10:04:05 INFO   main.go:42      server started port=8080
+//
+// The timestamps are written as set with SetTimeOptions, unless
+// RelativeTime is set. The caller column grows up to the widest caller seen.
+func NewConsoleSink(opts ConsoleOptions) Sink {
+	return newConsoleSink(opts)
+}
+
+func newConsoleSink(opts ConsoleOptions) *consoleSink {
+	s := &consoleSink{
+		opts:   opts,
+		out:    opts.Out,
+		err:    opts.Err,
+		stderr: map[Level]bool{},
+		colors: map[Level]string{},
+	}
+	if s.out == nil {
+		s.out = os.Stdout
+	}
+	if s.err == nil {
+		s.err = os.Stderr
+	}
+	for _, l := range opts.Stderr {
+		s.stderr[l] = true
+	}
+	for l, c := range gConsoleColors {
+		s.colors[l] = c
+	}
+	for l, c := range opts.Colors {
+		s.colors[l] = c
+	}
+	s.colorOut = useColor(opts.Color, s.out)
+	s.colorErr = useColor(opts.Color, s.err)
+	return s
+}
+
+type consoleSink struct {
+	opts     ConsoleOptions
+	out      io.Writer
+	err      io.Writer
+	stderr   map[Level]bool
+	colors   map[Level]string
+	colorOut bool
+	colorErr bool
+
+	lock        sync.Mutex
+	callerWidth int
+}
+
+// useColor reports whether the output written to w is colored.
+func useColor(mode ColorMode, w io.Writer) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is a character device, e.g. a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// allows reports whether logs of the level are written, from MinLevel on.
+func (s *consoleSink) allows(conf *config, level Level) bool {
+	if !conf.validLevel(int(level)) {
+		return false
+	}
+	return !conf.validLevel(int(s.opts.MinLevel)) || conf.levels[level].rank >= conf.levels[s.opts.MinLevel].rank
+}
+
+// writer returns the writer of the level and whether it is colored.
+func (s *consoleSink) writer(level Level) (io.Writer, bool) {
+	if s.stderr[level] {
+		return s.err, s.colorErr
+	}
+	return s.out, s.colorOut
+}
+
+// writeLine writes a line encoded in a structured format as it is.
+func (s *consoleSink) writeLine(conf *config, level Level, line []byte) {
+	if !s.allows(conf, level) {
+		return
+	}
+	w, _ := s.writer(level)
+	s.lock.Lock()
+	w.Write(line)
+	s.lock.Unlock()
+}
+
+func (s *consoleSink) Log(e Entry) {
+	conf := loadConf()
+	if !s.allows(conf, e.Level) {
+		return
+	}
+	w, color := s.writer(e.Level)
+
+	buf := gBufPool.getBuffer()
+	defer gBufPool.returnBuffer(buf)
+
+	if s.opts.RelativeTime {
+		fmt.Fprintf(buf, "%10.3f", e.Time.Sub(gStartTime).Seconds())
+	} else {
+		n := buf.timestamp(0, e.Time, &conf.timeOpts)
+		buf.Write(buf.tmp[:n])
+	}
+	buf.WriteByte(' ')
+
+	name := strings.ToUpper(conf.levels[e.Level].name)
+	width := 0
+	for _, d := range conf.levels {
+		if len(d.name) > width {
+			width = len(d.name)
+		}
+	}
+	writeColored(buf, color, s.colors[e.Level], name)
+	pad(buf, width-len(name)+1)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	caller := e.Caller.String()
+	if len(caller) > s.callerWidth {
+		s.callerWidth = len(caller)
+		if s.callerWidth > maxConsoleCallerWidth {
+			s.callerWidth = maxConsoleCallerWidth
+		}
+	}
+	buf.WriteString(caller)
+	pad(buf, s.callerWidth-len(caller)+1)
+
+	buf.WriteString(e.Message)
+	if len(e.Fields) > 0 {
+		buf.WriteByte(' ')
+		writeColored(buf, color, "2", formatMeta(e.Fields))
+	}
+	buf.WriteByte('\n')
+	w.Write(buf.Bytes())
+}
+
+// writeColored writes text in the color of the SGR parameters sgr.
+func writeColored(buf *buffer, color bool, sgr, text string) {
+	if !color || sgr == "" {
+		buf.WriteString(text)
+		return
+	}
+	buf.WriteString("\x1b[")
+	buf.WriteString(sgr)
+	buf.WriteByte('m')
+	buf.WriteString(text)
+	buf.WriteString("\x1b[0m")
+}
+
+// pad writes n spaces, at least one.
+func pad(buf *buffer, n int) {
+	if n < 1 {
+		n = 1
+	}
+	for ; n > 0; n-- {
+		buf.WriteByte(' ')
+	}
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleSink(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+	if err := SetTimeOptions(TimeOptions{Precision: 3}); err != nil {
+		t.Fatalf("SetTimeOptions failed: %v", err)
+	}
+	var out, errOut bytes.Buffer
+	s := NewConsoleSink(ConsoleOptions{
+		MinLevel: LevelQuery,
+		Stderr:   []Level{LevelError},
+		Color:    ColorNever,
+		Out:      &out,
+		Err:      &errOut,
+	})
+
+	at := time.Date(2022, 8, 1, 10, 4, 5, 123e6, time.UTC)
+	s.Log(Entry{Level: LevelDebug, Time: at, Message: "muted"})
+	s.Log(Entry{Level: LevelInfo, Time: at, Message: "started", Fields: Fields{"port": 8080},
+		Caller: Caller{File: "/src/main.go", Line: 42}})
+	s.Log(Entry{Level: LevelWarn, Time: at, Message: "slow", Caller: Caller{File: "/src/a.go", Line: 7}})
+	s.Log(Entry{Level: LevelError, Time: at, Message: "failed", Caller: Caller{File: "/src/a.go", Line: 8}})
+
+	want := "10:04:05.123 INFO   main.go:42 started port=8080\n" +
+		"10:04:05.123 WARN   a.go:7     slow\n"
+	if out.String() != want {
+		t.Fatalf("unexpected stdout:\n%q\nwant:\n%q", out.String(), want)
+	}
+	if errOut.String() != "10:04:05.123 ERROR  a.go:8     failed\n" {
+		t.Fatalf("unexpected stderr:\n%q", errOut.String())
+	}
+}
+
+func TestConsoleSinkColor(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+	var out bytes.Buffer
+	s := NewConsoleSink(ConsoleOptions{
+		Color:        ColorAlways,
+		Colors:       map[Level]string{LevelInfo: "1;32"},
+		RelativeTime: true,
+		Out:          &out,
+	})
+	s.Log(Entry{Level: LevelInfo, Time: gStartTime.Add(1500 * time.Millisecond), Message: "hi", Fields: Fields{"a": 1}})
+	s.Log(Entry{Level: LevelWarn, Time: gStartTime.Add(2 * time.Second), Message: "ho"})
+
+	want := "     1.500 \x1b[1;32mINFO\x1b[0m    hi \x1b[2ma=1\x1b[0m\n" +
+		"     2.000 \x1b[33mWARN\x1b[0m    ho\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%q\nwant:\n%q", out.String(), want)
+	}
+}
+
+func TestConsoleColorDetection(t *testing.T) {
+	var out bytes.Buffer
+	if useColor(ColorAuto, &out) || !useColor(ColorAlways, &out) {
+		t.Fatalf("expected colors for a terminal only, unless always")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "console")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	defer f.Close()
+	if isTerminal(f) {
+		t.Fatalf("expected a regular file not to be a terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(ColorAuto, os.Stdout) {
+		t.Fatalf("expected no colors with NO_COLOR")
+	}
+}
+
+func TestLogToConsole(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	var out, errOut bytes.Buffer
+	SetConsoleOptions(ConsoleOptions{MinLevel: LevelWarn, Stderr: []Level{LevelError}, Out: &out, Err: &errOut})
+	SetLogToConsole(true)
+
+	Info("quiet")
+	Warn("loud")
+	Error("failed")
+
+	if !strings.Contains(out.String(), "WARN") || !strings.Contains(out.String(), "console_test.go:") ||
+		!strings.HasSuffix(out.String(), " loud\n") || strings.Contains(out.String(), "quiet") {
+		t.Fatalf("unexpected stdout:\n%s", out.String())
+	}
+	if !strings.HasSuffix(errOut.String(), " failed\n") {
+		t.Fatalf("unexpected stderr:\n%s", errOut.String())
+	}
+}
+
+func TestLogToConsoleStructured(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	var out, errOut bytes.Buffer
+	SetConsoleOptions(ConsoleOptions{MinLevel: LevelWarn, Stderr: []Level{LevelError}, Color: ColorAlways, Out: &out, Err: &errOut})
+	SetLogFormat(FormatJSON)
+	SetLogToConsole(true)
+
+	Info("quiet")
+	Warn("loud")
+	Error("failed")
+
+	if strings.Count(out.String(), "\n") != 1 || !strings.HasPrefix(out.String(), `{"time":`) || !strings.Contains(out.String(), `"msg":"loud"`) {
+		t.Fatalf("unexpected stdout:\n%s", out.String())
+	}
+	if strings.Count(errOut.String(), "\n") != 1 || !strings.Contains(errOut.String(), `"msg":"failed"`) {
+		t.Fatalf("unexpected stderr:\n%s", errOut.String())
+	}
+}
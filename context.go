@@ -0,0 +1,113 @@
+package log
+
+import "context"
+
+// Fields are key/value pairs attached to a log. They are written after the
+// message in key order.
+type Fields map[string]interface{}
+
+// String formats the fields as written in the text lines: space separated
+// key=value pairs in key order, the stack, if any, on its own lines.
+func (f Fields) String() string {
+	return formatMeta(f)
+}
+
+// RequestIDField is the field name the request ID is logged with.
+const RequestIDField = "request_id"
+
+type contextKey int
+
+const fieldsKey contextKey = iota
+
+// ContextWithFields returns a copy of ctx carrying fields in addition to the
+// fields already carried by ctx. The context-aware log functions, e.g.
+// InfoContext, write them with every log.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	parent := FieldsFromContext(ctx)
+	merged := make(Fields, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey, merged)
+}
+
+// FieldsFromContext returns the fields carried by ctx. The returned map must
+// not be modified.
+func FieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey).(Fields)
+	return fields
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID, which is
+// logged as the RequestIDField field.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return ContextWithFields(ctx, Fields{RequestIDField: id})
+}
+
+// RequestIDFromContext returns the request ID carried by ctx or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := FieldsFromContext(ctx)[RequestIDField].(string)
+	return id
+}
+
+// TraceContext works like Trace and writes the fields carried by ctx.
+func TraceContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelTrace, format, args)
+}
+
+// InfoContext works like Info and writes the fields carried by ctx.
+func InfoContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelInfo, format, args)
+}
+
+// UpdateContext works like Update and writes the fields carried by ctx.
+func UpdateContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelUpdate, format, args)
+}
+
+// WarnContext works like Warn and writes the fields carried by ctx.
+func WarnContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelWarn, format, args)
+}
+
+// ErrorContext works like Error and writes the fields carried by ctx.
+func ErrorContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelError, format, args)
+}
+
+// PanicContext works like Panic and writes the fields carried by ctx.
+func PanicContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelPanic, format, args)
+}
+
+// AbortContext works like Abort and writes the fields carried by ctx.
+func AbortContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelAbort, format, args)
+}
+
+// QueryContext works like Query and writes the fields carried by ctx.
+func QueryContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelQuery, format, args)
+}
+
+// DebugContext works like Debug and writes the fields carried by ctx.
+func DebugContext(ctx context.Context, format string, args ...interface{}) {
+	logContext(ctx, logLevelDebug, format, args)
+}
+
+// LogContext works like Log and writes the fields carried by ctx.
+func LogContext(ctx context.Context, level Level, format string, args ...interface{}) {
+	if level.valid() {
+		logContext(ctx, int(level), format, args)
+	}
+}
+
+func logContext(ctx context.Context, logLevel int, format string, args []interface{}) {
+	LoggerFromContext(ctx).logf(logLevel, 1, FieldsFromContext(ctx), format, args)
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -75,3 +76,77 @@ func TestEnrichHTTPMeta_StackIncludedFor5xx(t *testing.T) {
 		t.Fatalf("stack does not appear to contain test frame: %v", st)
 	}
 }
+
+func TestEnrichHTTPMetaWithOptions_HeadersAndRedaction(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/pay?token=secret&id=5&t%6Fken=other&TOKEN", nil)
+	req.Header.Set("Authorization", "Bearer xyz")
+	req.Header.Set("X-Tenant", "acme")
+
+	opts := DefaultHTTPMetaOptions()
+	opts.Headers = []string{"Authorization", "X-Tenant"}
+	opts.RedactQuery = []string{"token"}
+
+	meta := EnrichHTTPMetaWithOptions(400, req, nil, 1, opts)
+	if meta["Authorization"] != Redacted {
+		t.Fatalf("expected Authorization redacted, got %v", meta["Authorization"])
+	}
+	if meta["X-Tenant"] != "acme" {
+		t.Fatalf("expected X-Tenant acme, got %v", meta["X-Tenant"])
+	}
+	if meta["query"] != "token=REDACTED&id=5&t%6Fken=REDACTED&TOKEN=REDACTED" {
+		t.Fatalf("expected token redacted in query, got %v", meta["query"])
+	}
+	if _, ok := meta["User-Agent"]; ok {
+		t.Fatalf("expected only configured headers to be copied")
+	}
+}
+
+func TestEnrichHTTPMetaWithOptions_ClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:5555"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.7")
+
+	meta := EnrichHTTPMeta(400, req, nil, 1)
+	if meta["clientIP"] != "10.0.0.2" {
+		t.Fatalf("expected untrusted remote address as client IP, got %v", meta["clientIP"])
+	}
+
+	opts := DefaultHTTPMetaOptions()
+	opts.TrustedProxies = []string{"10.0.0.0/8"}
+	meta = EnrichHTTPMetaWithOptions(400, req, nil, 1, opts)
+	if meta["clientIP"] != "203.0.113.9" {
+		t.Fatalf("expected client IP from X-Forwarded-For, got %v", meta["clientIP"])
+	}
+
+	req.Header.Del("X-Forwarded-For")
+	req.Header.Set("X-Real-IP", "198.51.100.4")
+	meta = EnrichHTTPMetaWithOptions(400, req, nil, 1, opts)
+	if meta["clientIP"] != "198.51.100.4" {
+		t.Fatalf("expected client IP from X-Real-IP, got %v", meta["clientIP"])
+	}
+}
+
+func TestEnrichHTTPMetaWithOptions_StackAndBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+
+	opts := DefaultHTTPMetaOptions()
+	opts.StackThreshold = 400
+	opts.StackDepth = 2
+	opts.MaxBodyBytes = 4
+
+	meta := EnrichHTTPMetaWithOptions(404, req, nil, 1, opts)
+	st, _ := meta["stack"].(string)
+	if n := strings.Count(st, "\n"); n != 2 {
+		t.Fatalf("expected 2 stack frames, got %d:\n%s", n, st)
+	}
+	if !strings.Contains(st, "TestEnrichHTTPMetaWithOptions_StackAndBody") {
+		t.Fatalf("expected stack to start at the caller, got:\n%s", st)
+	}
+	if meta["body"] != "0123" || meta["bodyTruncated"] != true {
+		t.Fatalf("expected truncated body 0123, got %v %v", meta["body"], meta["bodyTruncated"])
+	}
+	rest, _ := io.ReadAll(req.Body)
+	if string(rest) != "0123456789" {
+		t.Fatalf("expected request body restored, got %q", rest)
+	}
+}
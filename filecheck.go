@@ -0,0 +1,82 @@
+package log
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"time"
+)
+
+// DefFileCheckInterval is the default interval between the checks of the
+// current logfiles.
+const DefFileCheckInterval = time.Second
+
+// SetFileCheckInterval sets how often a logfile being written is checked to
+// still be the file at its name. A logfile deleted or moved away, e.g. on a
+// remounted disk, is reopened by name and its symlink is repaired. Zero turns
+// the checks off. By default, the logfiles are checked every second.
+func SetFileCheckInterval(d time.Duration) {
+	updateConf(func(conf *config) { conf.checkInterval = d })
+}
+
+// SetCopyTruncate sets whether the checks of the logfiles detect a truncation,
+// as done by logrotate with the copytruncate option, so the size of a
+// truncated logfile is counted anew for the size-based rotation.
+// By default, truncations are not detected.
+func SetCopyTruncate(on bool) {
+	setFlags(flagCopyTruncate, on)
+}
+
+func (conf *config) copyTruncate() bool {
+	return (conf.logflags & flagCopyTruncate) != 0
+}
+
+// check checks the current file at most once per check interval. It must be
+// called with l.lock held and a file open.
+func (l *logger) check(conf *config, t time.Time) {
+	if conf.checkInterval <= 0 {
+		return
+	}
+	if since := t.Sub(l.checked); since >= 0 && since < conf.checkInterval {
+		return
+	}
+	l.checked = t
+
+	fi, err := l.file.Stat()
+	if err != nil {
+		return
+	}
+	fs := conf.filesystem()
+	if cur, err := fs.Stat(l.filename); err != nil || !sameFile(fi, cur) {
+		// deleted or moved away, the directory may be gone as well
+		if err := fs.MkdirAll(conf.logPath, 0755); err != nil {
+			l.errlog(conf, t, nil, err)
+			return
+		}
+		if err := l.reopenFile(conf); err != nil {
+			l.errlog(conf, t, nil, err)
+			return
+		}
+		if fi, err = l.file.Stat(); err != nil {
+			return
+		}
+	} else if conf.copyTruncate() && fi.Size() < l.size {
+		l.size = fi.Size()
+	}
+
+	symlink := conf.symlink(l.level)
+	if link, err := fs.Stat(symlink); err != nil || !sameFile(fi, link) {
+		_ = fs.RemoveAll(symlink)
+		_ = fs.Symlink(path.Base(l.filename), symlink)
+	}
+}
+
+// sameFile reports whether a and b describe the same file, by device and
+// inode for the files of the operating system, otherwise by their Sys.
+func sameFile(a, b os.FileInfo) bool {
+	if os.SameFile(a, b) {
+		return true
+	}
+	sys := a.Sys()
+	return sys != nil && reflect.TypeOf(sys).Comparable() && sys == b.Sys()
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"flag"
+	"sort"
+	"sync"
+)
+
+// DefLogDir is the log directory used by InitFromFlags when none is given.
+const DefLogDir = "./log"
+
+// FlagPrefix is the prefix of the flags registered by RegisterFlags.
+const FlagPrefix = "log."
+
+var gFlagUsages = map[string]string{
+	"dir":            "directory to write the logfiles to",
+	"maxdays":        "maximum days to keep the logfiles",
+	"level":          "trace, debug or info: trace turns on trace and debug logs, debug turns on debug logs",
+	"trace":          "write trace logs",
+	"debug":          "write debug logs",
+	"through":        "write logs to the logfiles of the less severe levels too",
+	"console":        "write logs to the console too",
+	"funcname":       "write the function name of the caller",
+	"linenum":        "write the filename and line number of the caller",
+	"username":       "user name written with every log",
+	"filenameprefix": "prefix of the logfiles, with the %P, %H and %U placeholders",
+	"symlinkprefix":  "prefix of the symlinks to the logfiles, with the %P, %H and %U placeholders",
+	"format":         "output format: text, json or logfmt",
+	"maxfilesize":    "rotate a logfile exceeding the size, e.g. 100MB, 0 for unlimited",
+	"checkinterval":  "how often a logfile is checked to be still in place, 0 for never",
+	"copytruncate":   "detect logfiles truncated by logrotate with copytruncate",
+	"sinks":          "comma-separated names of the registered sinks to write to",
+	"vmodule":        "trace and debug verbosity per file, e.g. payments/*=trace,gorm=off",
+	"backtraceat":    "comma-separated file:line locations a log written from appends its stack",
+	"allfile":        "write every log to the combined all logfile too",
+	"timeformat":     "timestamp of the text lines: time, date or rfc3339",
+	"timeprecision":  "fraction of a second of the timestamps: s, ms, us or ns",
+	"timezone":       "time zone of the timestamps, e.g. UTC, default local",
+}
+
+// gFlagDefaults are the defaults shown in the usage of the flags.
+var gFlagDefaults = map[string]string{
+	"dir":           DefLogDir,
+	"maxdays":       "30",
+	"through":       "true",
+	"linenum":       "true",
+	"format":        "text",
+	"checkinterval": DefFileCheckInterval.String(),
+	"timeformat":    "time",
+	"timeprecision": "s",
+}
+
+// configFlagKey is the key of the flag naming a configuration file.
+const configFlagKey = "config"
+
+// gFlags are the values of the flags set on the command line.
+var gFlags = struct {
+	lock   sync.Mutex
+	values map[string]string
+}{values: map[string]string{}}
+
+type configFlag struct {
+	key   string
+	value string
+}
+
+func (f *configFlag) String() string {
+	return f.value
+}
+
+func (f *configFlag) Set(s string) error {
+	if f.key != configFlagKey {
+		// fail on the command line rather than on InitFromFlags
+		if err := new(FileConfig).set(f.key, s); err != nil {
+			return err
+		}
+	}
+	f.value = s
+	gFlags.lock.Lock()
+	gFlags.values[f.key] = s
+	gFlags.lock.Unlock()
+	return nil
+}
+
+func (f *configFlag) IsBoolFlag() bool {
+	switch f.key {
+	case "trace", "debug", "through", "console", "funcname", "linenum", "copytruncate", "allfile":
+		return true
+	}
+	return false
+}
+
+// RegisterFlags registers the flags configuring the logger in fs, or in
+// flag.CommandLine if fs is nil: -log.config naming a configuration file read
+// by LoadConfig, then -log.dir, -log.maxdays, -log.level, -log.trace,
+// -log.debug, -log.console, -log.format, -log.vmodule and a flag for every other key of a
+// configuration file. InitFromFlags applies them once fs is parsed.
+func RegisterFlags(fs *flag.FlagSet) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	fs.Var(&configFlag{key: configFlagKey}, FlagPrefix+configFlagKey, "configuration file, see LoadConfig")
+	for _, key := range gConfigKeys {
+		fs.Var(&configFlag{key: key, value: gFlagDefaults[key]}, FlagPrefix+key, gFlagUsages[key])
+	}
+}
+
+// InitFromFlags initializes the logger with the flags registered by
+// RegisterFlags: the configuration file of -log.config is loaded with the
+// environment variable overrides, then the flags set on the command line are
+// applied over them. The logfiles are written to DefLogDir unless a directory
+// is given.
+func InitFromFlags() error {
+	gFlags.lock.Lock()
+	values := make(map[string]string, len(gFlags.values))
+	for k, v := range gFlags.values {
+		values[k] = v
+	}
+	gFlags.lock.Unlock()
+
+	cfg, err := LoadConfig(values[configFlagKey])
+	if err != nil {
+		return err
+	}
+	delete(values, configFlagKey)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := cfg.set(key, values[key]); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Dir == "" {
+		cfg.Dir = DefLogDir
+	}
+	return cfg.Apply()
+}
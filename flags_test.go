@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+
+	dir := t.TempDir()
+	cfgFile := writeConfig(t, t.TempDir(), "log.yaml", "maxdays: 5\nconsole: true\nformat: logfmt\n")
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	RegisterFlags(fs)
+	err := fs.Parse([]string{"-log.config", cfgFile, "-log.dir", dir, "-log.debug", "-log.console=false", "-log.maxfilesize", "1KB", "-log.filenameprefix", "app", "-log.vmodule", "payments/*=trace"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if err := InitFromFlags(); err != nil {
+		t.Fatalf("InitFromFlags failed: %v", err)
+	}
+
+	conf := loadConf()
+	if conf.logPath != dir+"/" || conf.maxdays != 5 || !conf.logDebug() || conf.logTrace() ||
+		conf.logToConsole() || conf.format != FormatLogfmt || conf.maxFileSize != 1024 || conf.filenamePrefix != "app" || GetVModule() != "payments/*=trace" {
+		t.Fatalf("unexpected settings: %+v", conf)
+	}
+	if conf.hostName == "" {
+		t.Fatal("expected the host name set")
+	}
+}
+
+func TestRegisterFlags_InvalidValueAndUsage(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"-log.format", "xml"}); err == nil || !strings.Contains(err.Error(), "unknown log format") {
+		t.Fatalf("expected an error for an invalid format, got %v", err)
+	}
+
+	out.Reset()
+	fs.PrintDefaults()
+	for _, want := range []string{"-log.dir value", "(default ./log)", "-log.trace", "-log.maxdays value"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in usage:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the output format of the logs.
+type Format int
+
+// output formats
+const (
+	// FormatText writes the classic `L15:04:05 file.go:12 host] message` lines
+	// with fields appended as key=value pairs.
+	FormatText Format = iota
+	// FormatJSON writes one JSON object per line.
+	FormatJSON
+	// FormatLogfmt writes one line of logfmt key=value pairs.
+	FormatLogfmt
+)
+
+// structuredTimeLayout is the time format of FormatJSON and FormatLogfmt.
+const structuredTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+var gFormatNames = []string{"text", "json", "logfmt"}
+
+// String returns the name of the format.
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(gFormatNames) {
+		return "unknown"
+	}
+	return gFormatNames[f]
+}
+
+// ParseFormat returns the format with the given name: text, json or logfmt.
+func ParseFormat(name string) (Format, error) {
+	for i, n := range gFormatNames {
+		if strings.EqualFold(n, name) {
+			return Format(i), nil
+		}
+	}
+	return FormatText, fmt.Errorf("unknown log format %q", name)
+}
+
+// SetLogFormat sets the output format of the logs.
+// By default, logs are written with FormatText.
+func SetLogFormat(format Format) {
+	updateConf(func(conf *config) { conf.format = format })
+}
+
+// writeEntry writes a whole log line in the configured format into buf.
+func writeEntry(buf *buffer, conf *config, logLevel, skip int, t time.Time, fields Fields, format string, args []interface{}) {
+	switch conf.format {
+	case FormatJSON, FormatLogfmt:
+		genStructured(buf, conf, logLevel, skip+1, t, fmt.Sprintf(format, args...), fields)
+	default:
+		genLogPrefix(buf, conf, logLevel, skip+1, t)
+		fmt.Fprintf(buf, format, args...)
+		if len(fields) > 0 {
+			buf.WriteByte(' ')
+			buf.WriteString(formatMeta(fields))
+		}
+	}
+	buf.WriteByte('\n')
+}
+
+// gReservedKeys are the keys written by genStructured before the fields.
+var gReservedKeys = map[string]bool{
+	"time": true, "level": true, "caller": true, "func": true, "host": true, "user": true, "msg": true,
+}
+
+// genStructured writes a JSON or logfmt line. The keys are written in a stable
+// order: time, level, caller, func, host, user, msg, then the fields in key
+// order. A field named as one of the keys written first is prefixed with
+// `fields.`, e.g. fields.level, so the line has no duplicate keys.
+func genStructured(buf *buffer, conf *config, logLevel, skip int, t time.Time, msg string, fields Fields) {
+	enc := structuredEncoder{buf: buf, json: conf.format == FormatJSON}
+	enc.begin()
+	if conf.timeOpts.Location != nil {
+		t = t.In(conf.timeOpts.Location)
+	}
+	enc.add("time", t.Format(structuredTimeLayout))
+	enc.add("level", conf.levels[logLevel].name)
+
+	var pc uintptr
+	var ok bool
+	if conf.logFilenameLineNum() {
+		var file string
+		var line int
+		pc, file, line, ok = runtime.Caller(skip)
+		if ok {
+			enc.add("caller", path.Base(file)+":"+strconv.Itoa(line))
+		}
+	}
+	if conf.logFuncName() {
+		if !ok {
+			pc, _, _, ok = runtime.Caller(skip)
+		}
+		if ok {
+			enc.add("func", runtime.FuncForPC(pc).Name())
+		}
+	}
+	if conf.hostName != "" {
+		enc.add("host", conf.hostName)
+	}
+	if conf.userName != "" {
+		enc.add("user", conf.userName)
+	}
+	enc.add("msg", msg)
+
+	for _, k := range sortedKeys(fields) {
+		key := k
+		if gReservedKeys[k] {
+			key = "fields." + k
+		}
+		enc.add(key, fields[k])
+	}
+	enc.end()
+}
+
+type structuredEncoder struct {
+	buf   *buffer
+	json  bool
+	count int
+}
+
+func (e *structuredEncoder) begin() {
+	if e.json {
+		e.buf.WriteByte('{')
+	}
+}
+
+func (e *structuredEncoder) end() {
+	if e.json {
+		e.buf.WriteByte('}')
+	}
+}
+
+func (e *structuredEncoder) add(key string, value interface{}) {
+	if e.count > 0 {
+		if e.json {
+			e.buf.WriteByte(',')
+		} else {
+			e.buf.WriteByte(' ')
+		}
+	}
+	e.count++
+
+	if e.json {
+		e.buf.WriteString(strconv.Quote(key))
+		e.buf.WriteByte(':')
+		e.buf.Write(jsonValue(value))
+		return
+	}
+	e.buf.WriteString(key)
+	e.buf.WriteByte('=')
+	e.buf.WriteString(logfmtValue(value))
+}
+
+// jsonValue encodes v, errors and fmt.Stringers are written as strings.
+func jsonValue(v interface{}) []byte {
+	switch tv := v.(type) {
+	case error:
+		v = tv.Error()
+	case fmt.Stringer:
+		v = tv.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return data
+}
+
+// logfmtValue formats v, quoting it when it's empty or contains spaces, quotes,
+// equal signs or control characters.
+func logfmtValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatMeta formats meta as space separated key=value pairs in key order.
+// The stack, if any, is appended on its own lines.
+func formatMeta(meta map[string]interface{}) string {
+	var b strings.Builder
+	for _, k := range sortedKeys(meta) {
+		if k == "stack" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(meta[k]))
+	}
+	if st, ok := meta["stack"]; ok {
+		b.WriteByte('\n')
+		b.WriteString(strings.TrimRight(fmt.Sprint(st), "\n"))
+	}
+	return b.String()
+}
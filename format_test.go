@@ -0,0 +1,115 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func initFormatTest(t *testing.T, format Format) string {
+	t.Helper()
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("fmt", "fmt")
+	SetLogFormat(format)
+	return dir
+}
+
+func TestLogHTTPError_LevelFromStatus(t *testing.T) {
+	dir := initFormatTest(t, FormatText)
+	SetLogThrough(false)
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	LogHTTPError(404, req, errors.New("no such item"), nil)
+	LogHTTPError(503, req, errors.New("db down"), map[string]interface{}{"attempt": 2})
+
+	warn := readLogFile(t, dir, "fmt.warn")
+	if !strings.Contains(warn, "HTTP 404 Not Found") || !strings.Contains(warn, `error="no such item"`) || !strings.Contains(warn, "status=404") {
+		t.Fatalf("unexpected warn log:\n%s", warn)
+	}
+	if !strings.Contains(warn, "format_test.go") {
+		t.Fatalf("expected caller of LogHTTPError in the prefix:\n%s", warn)
+	}
+	errLog := readLogFile(t, dir, "fmt.error")
+	if !strings.Contains(errLog, "HTTP 503") || !strings.Contains(errLog, "attempt=2") || !strings.Contains(errLog, "path=/items/1") {
+		t.Fatalf("unexpected error log:\n%s", errLog)
+	}
+}
+
+func TestFormatJSON_StableKeyOrder(t *testing.T) {
+	dir := initFormatTest(t, FormatJSON)
+
+	LogHTTPError(500, httptest.NewRequest(http.MethodPost, "/x", nil), errors.New("boom"), nil)
+
+	line := strings.SplitN(readLogFile(t, dir, "fmt.error"), "\n", 2)[0]
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", line, err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "HTTP 500 Internal Server Error" || entry["method"] != "POST" || entry["status"] != float64(500) {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+	if entry["stack"] == nil {
+		t.Fatalf("expected stack field for 500")
+	}
+
+	keys := []string{`"time"`, `"level"`, `"caller"`, `"msg"`, `"error"`, `"hostname"`, `"method"`, `"path"`, `"status"`}
+	last := -1
+	for _, k := range keys {
+		i := strings.Index(line, k+":")
+		if i < 0 || i < last {
+			t.Fatalf("expected key %s in stable order in %s", k, line)
+		}
+		last = i
+	}
+}
+
+func TestFormatLogfmt(t *testing.T) {
+	dir := initFormatTest(t, FormatLogfmt)
+
+	Info("hello %s", "world")
+
+	line := readLogFile(t, dir, "fmt.info")
+	if !strings.HasPrefix(line, "time=") || !strings.Contains(line, ` level=info caller=format_test.go:`) || !strings.Contains(line, ` msg="hello world"`) {
+		t.Fatalf("unexpected logfmt line %q", line)
+	}
+}
+
+func TestFormatJSON_ReservedFieldKeys(t *testing.T) {
+	dir := initFormatTest(t, FormatJSON)
+
+	ctx := ContextWithFields(context.Background(), Fields{"level": "high", "msg": "field", "time": 3, "order": 7})
+	InfoContext(ctx, "reserved")
+
+	line := readLogFile(t, dir, "fmt.info")
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", line, err)
+	}
+	if entry["level"] != "info" || entry["msg"] != "reserved" || entry["fields.level"] != "high" ||
+		entry["fields.msg"] != "field" || entry["fields.time"] != float64(3) || entry["order"] != float64(7) {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+	if strings.Count(line, `"level":`) != 1 || strings.Count(line, `"msg":`) != 1 {
+		t.Fatalf("expected no duplicate keys in %s", line)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range []Format{FormatText, FormatJSON, FormatLogfmt} {
+		got, err := ParseFormat(f.String())
+		if err != nil || got != f {
+			t.Fatalf("ParseFormat(%q) = %v, %v", f.String(), got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,372 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clock tells the time used for the logs, the rotation and the purging.
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock which also runs the timers of the package, e.g. the
+// restores of AdminHandler, so a fake clock of the tests fires them when it is
+// advanced. The timers of a plain Clock follow the system time.
+type TimerClock interface {
+	Clock
+	// AfterFunc calls f once d has elapsed on the clock and returns a
+	// function cancelling the call, which reports whether it did.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// FS is the filesystem the logfiles and the symlinks are written to.
+type FS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Remove(name string) error
+	RemoveAll(path string) error
+	Symlink(oldname, newname string) error
+	Walk(root string, fn filepath.WalkFunc) error
+	// Stat returns the FileInfo of the file name, following a symlink. The
+	// FileInfos of a file must be the same file for os.SameFile or have
+	// equal Sys values.
+	Stat(name string) (os.FileInfo, error)
+}
+
+// File is a logfile opened by FS.
+type File interface {
+	io.Writer
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// SetClock sets the clock used for the logs, the rotation and the purging,
+// and for the timers of the package if it is a TimerClock.
+// By default, the system clock is used.
+func SetClock(c Clock) {
+	updateConf(func(conf *config) { conf.clock = c })
+}
+
+// SetFS sets the filesystem the logfiles are written to.
+// By default, the filesystem of the operating system is used.
+func SetFS(fs FS) {
+	updateConf(func(conf *config) { conf.fs = fs })
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+type osFS struct{}
+
+func (osFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) RemoveAll(path string) error                  { return os.RemoveAll(path) }
+func (osFS) Symlink(oldname, newname string) error        { return os.Symlink(oldname, newname) }
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) Walk(root string, fn filepath.WalkFunc) error { return filepath.Walk(root, fn) }
+func (osFS) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+
+// MemFS is an in-memory FS, for tests. Directories are implicit and the
+// modification times are taken from its clock.
+type MemFS struct {
+	clock    Clock
+	lock     sync.Mutex
+	files    map[string]*memFile
+	symlinks map[string]string
+}
+
+type memFile struct {
+	data    bytes.Buffer
+	modTime time.Time
+}
+
+// NewMemFS returns an empty MemFS, clock is the system clock if nil.
+func NewMemFS(clock Clock) *MemFS {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &MemFS{clock: clock, files: map[string]*memFile{}, symlinks: map[string]string{}}
+}
+
+// MkdirAll implements FS, directories are implicit.
+func (fs *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+// OpenFile implements FS. Only appending writes are supported.
+func (fs *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	name = filepath.Clean(name)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	f, ok := fs.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		f = &memFile{modTime: fs.clock.Now()}
+		fs.files[name] = f
+	} else if flag&os.O_TRUNC != 0 {
+		f.data.Reset()
+	}
+	return &memHandle{fs: fs, file: f}, nil
+}
+
+// Remove implements FS.
+func (fs *MemFS) Remove(name string) error {
+	name = filepath.Clean(name)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if _, ok := fs.files[name]; ok {
+		delete(fs.files, name)
+		return nil
+	}
+	if _, ok := fs.symlinks[name]; ok {
+		delete(fs.symlinks, name)
+		return nil
+	}
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+}
+
+// RemoveAll implements FS.
+func (fs *MemFS) RemoveAll(path string) error {
+	path = filepath.Clean(path)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	prefix := path + string(filepath.Separator)
+	for name := range fs.files {
+		if name == path || strings.HasPrefix(name, prefix) {
+			delete(fs.files, name)
+		}
+	}
+	for name := range fs.symlinks {
+		if name == path || strings.HasPrefix(name, prefix) {
+			delete(fs.symlinks, name)
+		}
+	}
+	return nil
+}
+
+// Symlink implements FS.
+func (fs *MemFS) Symlink(oldname, newname string) error {
+	newname = filepath.Clean(newname)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if _, ok := fs.files[newname]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	if _, ok := fs.symlinks[newname]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	fs.symlinks[newname] = oldname
+	return nil
+}
+
+// Walk implements FS, the regular files and the symlinks under root are
+// walked in lexical order.
+func (fs *MemFS) Walk(root string, fn filepath.WalkFunc) error {
+	root = filepath.Clean(root)
+	prefix := root + string(filepath.Separator)
+	fs.lock.Lock()
+	infos := map[string]os.FileInfo{}
+	for name, f := range fs.files {
+		if strings.HasPrefix(name, prefix) {
+			infos[name] = f.info(filepath.Base(name))
+		}
+	}
+	for name := range fs.symlinks {
+		if strings.HasPrefix(name, prefix) {
+			infos[name] = memFileInfo{name: filepath.Base(name), mode: os.ModeSymlink}
+		}
+	}
+	fs.lock.Unlock()
+
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := fn(name, infos[name], nil); err != nil && err != filepath.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stat implements FS. Two FileInfos of the same file have the same Sys.
+func (fs *MemFS) Stat(name string) (os.FileInfo, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	name, f, err := fs.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.info(filepath.Base(name)), nil
+}
+
+// ReadFile returns the content of the file name, following a symlink.
+func (fs *MemFS) ReadFile(name string) ([]byte, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	_, f, err := fs.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), f.data.Bytes()...), nil
+}
+
+// Truncate truncates the file name to size, as logrotate does with the
+// copytruncate option.
+func (fs *MemFS) Truncate(name string, size int64) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	_, f, err := fs.resolve("truncate", name)
+	if err != nil {
+		return err
+	}
+	if size < int64(f.data.Len()) {
+		f.data.Truncate(int(size))
+	}
+	return nil
+}
+
+// Rename renames the file oldname to newname, the handles opened keep
+// writing to it.
+func (fs *MemFS) Rename(oldname, newname string) error {
+	oldname, newname = filepath.Clean(oldname), filepath.Clean(newname)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	f, ok := fs.files[oldname]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	delete(fs.files, oldname)
+	fs.files[newname] = f
+	return nil
+}
+
+// resolve returns the file name, following a symlink. It must be called with
+// fs.lock held.
+func (fs *MemFS) resolve(op, name string) (string, *memFile, error) {
+	name = filepath.Clean(name)
+	if target, ok := fs.symlinks[name]; ok {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(name), target)
+		}
+		name = target
+	}
+	f, ok := fs.files[name]
+	if !ok {
+		return name, nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return name, f, nil
+}
+
+// Readlink returns the target of the symlink name.
+func (fs *MemFS) Readlink(name string) (string, error) {
+	name = filepath.Clean(name)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	target, ok := fs.symlinks[name]
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+	return target, nil
+}
+
+// Files returns the names of the regular files in lexical order.
+func (fs *MemFS) Files() []string {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	names := make([]string, 0, len(fs.files))
+	for name := range fs.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type memHandle struct {
+	fs     *MemFS
+	file   *memFile
+	closed bool
+}
+
+func (h *memHandle) Write(p []byte) (int, error) {
+	h.fs.lock.Lock()
+	defer h.fs.lock.Unlock()
+
+	if h.closed {
+		return 0, os.ErrClosed
+	}
+	h.file.modTime = h.fs.clock.Now()
+	return h.file.data.Write(p)
+}
+
+func (h *memHandle) Stat() (os.FileInfo, error) {
+	h.fs.lock.Lock()
+	defer h.fs.lock.Unlock()
+
+	if h.closed {
+		return nil, os.ErrClosed
+	}
+	return h.file.info(""), nil
+}
+
+func (h *memHandle) Close() error {
+	h.fs.lock.Lock()
+	defer h.fs.lock.Unlock()
+
+	if h.closed {
+		return os.ErrClosed
+	}
+	h.closed = true
+	return nil
+}
+
+func (f *memFile) info(name string) memFileInfo {
+	return memFileInfo{name: name, size: int64(f.data.Len()), modTime: f.modTime, file: f}
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	file    *memFile
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi memFileInfo) IsDir() bool        { return false }
+
+func (fi memFileInfo) Sys() interface{} {
+	if fi.file == nil {
+		return nil
+	}
+	return fi.file
+}
@@ -0,0 +1,225 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	lock   sync.Mutex
+	t      time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at   time.Time
+	f    func()
+	done bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	timer := &fakeTimer{at: c.t.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		stopped := !timer.done
+		timer.done = true
+		return stopped
+	}
+}
+
+// Add advances the clock, calling the functions of the timers due in order
+// before it returns.
+func (c *fakeClock) Add(d time.Duration) {
+	c.lock.Lock()
+	end := c.t.Add(d)
+	for {
+		var next *fakeTimer
+		for _, timer := range c.timers {
+			if !timer.done && !timer.at.After(end) && (next == nil || timer.at.Before(next.at)) {
+				next = timer
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.done = true
+		if next.at.After(c.t) {
+			c.t = next.at
+		}
+		c.lock.Unlock()
+		next.f()
+		c.lock.Lock()
+	}
+	c.t = end
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if !timer.done {
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+	c.lock.Unlock()
+}
+
+func initMemFS(t *testing.T, start time.Time) (*MemFS, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: start}
+	fs := NewMemFS(clock)
+	ResetForTests()
+	SetClock(clock)
+	SetFS(fs)
+	if err := Init("/logs", 30, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("mem", "mem")
+	t.Cleanup(ResetForTests)
+	return fs, clock
+}
+
+func TestMemFS_MidnightRotation(t *testing.T) {
+	fs, clock := initMemFS(t, time.Date(2024, 3, 1, 23, 59, 59, 0, time.Local))
+
+	Info("before midnight")
+	clock.Add(2 * time.Second)
+	Info("after midnight")
+
+	files := fs.Files()
+	if len(files) != 2 || !strings.Contains(files[0], "info_20240301.") || !strings.Contains(files[1], "info_20240302.") {
+		t.Fatalf("expected one file per day, got %v", files)
+	}
+	target, err := fs.Readlink("/logs/mem.info")
+	if err != nil || !strings.Contains(target, "info_20240302.") {
+		t.Fatalf("expected symlink to the current file, got %q %v", target, err)
+	}
+	data, err := fs.ReadFile("/logs/mem.info")
+	if err != nil || !strings.Contains(string(data), "I00:00:01") || !strings.Contains(string(data), "after midnight") {
+		t.Fatalf("unexpected current file %q %v", data, err)
+	}
+}
+
+func TestMemFS_PurgesOldFiles(t *testing.T) {
+	fs, clock := initMemFS(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local))
+
+	Info("old")
+	clock.Add(29 * 24 * time.Hour)
+	Warn("recent")
+	if n := len(fs.Files()); n != 3 {
+		t.Fatalf("expected 3 files before purging, got %v", fs.Files())
+	}
+
+	clock.Add(2 * 24 * time.Hour)
+	Info("new")
+
+	files := fs.Files()
+	for _, f := range files {
+		if strings.Contains(f, "20240301") {
+			t.Fatalf("expected files older than 30 days purged, got %v", files)
+		}
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected the warn files and the new info file, got %v", files)
+	}
+}
+
+func TestFileCheck_ReopensDeletedFile(t *testing.T) {
+	fs, clock := initMemFS(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local))
+	SetLogThrough(false)
+
+	Info("first")
+	files := fs.Files()
+	if err := fs.Remove(files[0]); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if err := fs.Remove("/logs/mem.info"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+
+	// the checks are rate-limited
+	clock.Add(100 * time.Millisecond)
+	Info("orphaned")
+	if n := len(fs.Files()); n != 0 {
+		t.Fatalf("expected no check within the interval, got %v", fs.Files())
+	}
+
+	clock.Add(time.Second)
+	Info("second")
+	if got := fs.Files(); len(got) != 1 || got[0] != files[0] {
+		t.Fatalf("expected the file reopened by name, got %v", got)
+	}
+	data, err := fs.ReadFile("/logs/mem.info")
+	if err != nil || string(data) == "" || strings.Contains(string(data), "orphaned") || !strings.Contains(string(data), "second") {
+		t.Fatalf("expected the symlink repaired to the reopened file, got %q %v", data, err)
+	}
+}
+
+func TestFileCheck_ReopensMovedFile(t *testing.T) {
+	fs, clock := initMemFS(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local))
+	SetLogThrough(false)
+
+	Info("first")
+	name := fs.Files()[0]
+	if err := fs.Rename(name, name+".1"); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	clock.Add(2 * time.Second)
+	Info("second")
+
+	if data, _ := fs.ReadFile(name + ".1"); strings.Contains(string(data), "second") {
+		t.Fatalf("expected no log written to the moved file, got %q", data)
+	}
+	if data, err := fs.ReadFile("/logs/mem.info"); err != nil || !strings.Contains(string(data), "second") {
+		t.Fatalf("expected the log in the reopened file, got %q %v", data, err)
+	}
+}
+
+func TestFileCheck_CopyTruncate(t *testing.T) {
+	for _, copyTruncate := range []bool{false, true} {
+		fs, clock := initMemFS(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local))
+		SetLogThrough(false)
+		SetMaxFileSizeBytes(200)
+		SetCopyTruncate(copyTruncate)
+
+		Info("%s", strings.Repeat("x", 120))
+		if err := fs.Truncate("/logs/mem.info", 0); err != nil {
+			t.Fatalf("truncate failed: %v", err)
+		}
+		clock.Add(2 * time.Second)
+		Info("%s", strings.Repeat("y", 120))
+
+		want := 2
+		if copyTruncate {
+			want = 1
+		}
+		if n := len(fs.Files()); n != want {
+			t.Fatalf("copytruncate=%v: expected %d files, got %v", copyTruncate, want, fs.Files())
+		}
+	}
+}
+
+func TestFileCheck_Disabled(t *testing.T) {
+	fs, clock := initMemFS(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local))
+	SetLogThrough(false)
+	SetFileCheckInterval(0)
+
+	Info("first")
+	if err := fs.Remove(fs.Files()[0]); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	clock.Add(time.Hour)
+	Info("second")
+	if n := len(fs.Files()); n != 0 {
+		t.Fatalf("expected no reopen with the checks off, got %v", fs.Files())
+	}
+}
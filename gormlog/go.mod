@@ -0,0 +1,13 @@
+module github.com/dainiauskas/go-log/gormlog
+
+go 1.17
+
+require (
+	github.com/dainiauskas/go-log v0.0.0-20261018220105-56ef8df92859
+	gorm.io/gorm v1.23.8
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.4 // indirect
+)
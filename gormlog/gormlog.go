@@ -0,0 +1,149 @@
+// Package gormlog adapts the go-log package to the logger.Interface of GORM v2.
+//
+// It lives in its own module, github.com/dainiauskas/go-log/gormlog, so the
+// core module does not depend on GORM:
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{
+//		Logger: gormlog.New(gormlog.DefaultConfig()),
+//	})
+package gormlog
+
+import (
+	"context"
+	"errors"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/dainiauskas/go-log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Config configures the Logger returned by New.
+type Config struct {
+	// LogLevel is the GORM log level, logger.Info logs every query.
+	LogLevel logger.LogLevel
+	// SlowThreshold escalates queries lasting at least that long to warn
+	// level, zero disables it.
+	SlowThreshold time.Duration
+	// IgnoreRecordNotFoundError skips logging gorm.ErrRecordNotFound errors.
+	IgnoreRecordNotFoundError bool
+}
+
+// DefaultConfig returns the configuration logging every query, escalating
+// queries slower than 200 milliseconds.
+func DefaultConfig() Config {
+	return Config{
+		LogLevel:      logger.Info,
+		SlowThreshold: 200 * time.Millisecond,
+	}
+}
+
+// Logger implements logger.Interface of GORM v2. Queries are written with
+// query level together with the fields carried by the context, slow queries
+// with warn level and failed ones with error level.
+type Logger struct {
+	config Config
+}
+
+// New returns a Logger with the given configuration.
+func New(config Config) *Logger {
+	return &Logger{config: config}
+}
+
+// LogMode returns a copy of the Logger with the given log level.
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	nl := *l
+	nl.config.LogLevel = level
+	return &nl
+}
+
+// Info logs with info level.
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Info {
+		log.InfoContext(ctx, msg, data...)
+	}
+}
+
+// Warn logs with warn level.
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Warn {
+		log.WarnContext(ctx, msg, data...)
+	}
+}
+
+// Error logs with error level.
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Error {
+		log.ErrorContext(ctx, msg, data...)
+	}
+}
+
+// Trace logs the SQL query returned by fc, which started at begin.
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.config.LogLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !(l.config.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound))
+	slow := l.config.SlowThreshold > 0 && elapsed >= l.config.SlowThreshold
+
+	logs := failed && l.config.LogLevel >= logger.Error ||
+		slow && l.config.LogLevel >= logger.Warn ||
+		l.config.LogLevel >= logger.Info
+	if !logs && !log.QueryStatsEnabled() {
+		// fc renders the SQL with the dialector, which is expensive
+		return
+	}
+
+	sql, rows := fc()
+	log.RecordQuery(sql, elapsed)
+
+	switch {
+	case failed && l.config.LogLevel >= logger.Error:
+		ctx = log.ContextWithFields(ctx, queryFields(rows, elapsed, err))
+		log.ErrorContext(ctx, "Query failed: %s", sql)
+	case slow && l.config.LogLevel >= logger.Warn:
+		ctx = log.ContextWithFields(ctx, queryFields(rows, elapsed, err))
+		log.WarnContext(ctx, "Slow query >= %v: %s", l.config.SlowThreshold, sql)
+	case l.config.LogLevel >= logger.Info:
+		ctx = log.ContextWithFields(ctx, queryFields(rows, elapsed, err))
+		log.QueryContext(ctx, "Query=[%s]", sql)
+	}
+}
+
+func queryFields(rows int64, elapsed time.Duration, err error) log.Fields {
+	fields := log.Fields{
+		"duration": elapsed,
+		"source":   source(),
+	}
+	if rows >= 0 {
+		fields["rows"] = rows
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	return fields
+}
+
+// source returns file:line of the first caller outside of GORM and this
+// package, which is the code running the query.
+func source() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		internal := strings.HasPrefix(f.Function, "gorm.io/") || strings.Contains(f.Function, "/go-log/gormlog.")
+		if !internal || strings.HasSuffix(f.File, "_test.go") {
+			return path.Base(f.File) + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package gormlog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/dainiauskas/go-log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var logDir string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gormlog-")
+	if err != nil {
+		panic(err)
+	}
+	logDir = dir
+	if err := log.Init(dir, 1, false); err != nil {
+		panic(err)
+	}
+	log.SetFilenamePrefix("gorm", "gorm")
+	log.SetLogThrough(false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func readLog(t *testing.T, level string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(logDir, "gorm."+level))
+	if err != nil {
+		t.Fatalf("failed to read %s log: %v", level, err)
+	}
+	return string(data)
+}
+
+func TestTrace_LogsQueryWithFields(t *testing.T) {
+	l := New(DefaultConfig())
+	ctx := log.ContextWithRequestID(context.Background(), "req-5")
+	l.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT * FROM users", 3 }, nil)
+
+	out := readLog(t, "query")
+	for _, want := range []string{"Query=[SELECT * FROM users]", "rows=3", "request_id=req-5", "source=gormlog_test.go:"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in query log, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestTrace_SlowAndFailedQueries(t *testing.T) {
+	l := New(Config{LogLevel: logger.Info, SlowThreshold: time.Millisecond, IgnoreRecordNotFoundError: true})
+	l.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) { return "SELECT slow", 1 }, nil)
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT broken", -1 }, errors.New("syntax error"))
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT missing", 0 }, gorm.ErrRecordNotFound)
+
+	if out := readLog(t, "warn"); !strings.Contains(out, "Slow query >= 1ms: SELECT slow") {
+		t.Fatalf("expected slow query in warn log, got:\n%s", out)
+	}
+	out := readLog(t, "error")
+	if !strings.Contains(out, "SELECT broken") || !strings.Contains(out, `error="syntax error"`) {
+		t.Fatalf("expected failed query in error log, got:\n%s", out)
+	}
+	if strings.Contains(out, "SELECT missing") {
+		t.Fatalf("expected ErrRecordNotFound to be ignored, got:\n%s", out)
+	}
+}
+
+func TestLogMode_Silent(t *testing.T) {
+	l := New(DefaultConfig()).LogMode(logger.Silent)
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	l.Info(context.Background(), "hidden %d", 1)
+
+	if data, _ := os.ReadFile(filepath.Join(logDir, "gorm.query")); strings.Contains(string(data), "SELECT 1") {
+		t.Fatalf("expected no query log in silent mode")
+	}
+}
+
+func TestTrace_RendersSQLOnlyWhenUsed(t *testing.T) {
+	l := New(Config{LogLevel: logger.Warn, SlowThreshold: time.Hour})
+	calls := 0
+	fc := func() (string, int64) {
+		calls++
+		return "SELECT rendered", 1
+	}
+
+	l.Trace(context.Background(), time.Now(), fc, nil)
+	if calls != 0 {
+		t.Fatalf("expected the SQL not rendered when nothing uses it, got %d calls", calls)
+	}
+
+	log.ResetQueryStats()
+	log.EnableQueryStats(0)
+	defer log.DisableQueryStats()
+	l.Trace(context.Background(), time.Now(), fc, nil)
+	if calls != 1 || len(log.QueryStats()) != 1 {
+		t.Fatalf("expected the SQL rendered for the query stats, got %d calls", calls)
+	}
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientLogOptions configures the transport returned by NewClientLogTransport.
+type ClientLogOptions struct {
+	// Level is the level of the logs of successful calls. The zero value,
+	// LevelTrace, is replaced by LevelInfo, as trace logs are muted by
+	// default.
+	Level Level
+	// SlowThreshold escalates calls lasting at least that long to warn level,
+	// unless Level is as severe or more, by Rank. Zero disables it.
+	SlowThreshold time.Duration
+	// RedactQuery are the query parameters whose values are replaced with
+	// Redacted in the logged URL.
+	RedactQuery []string
+	// MaxBodyBytes is the number of request and response body bytes logged,
+	// zero disables body capture. The response body is captured as the
+	// caller reads it, so the call is logged once the body is read to the
+	// end or closed.
+	MaxBodyBytes int64
+}
+
+// DefaultClientLogOptions returns the options logging calls with info level
+// and escalating calls slower than 5 seconds.
+func DefaultClientLogOptions() ClientLogOptions {
+	return ClientLogOptions{
+		Level:         LevelInfo,
+		SlowThreshold: 5 * time.Second,
+		RedactQuery:   []string{"token", "access_token", "api_key", "apikey", "password", "secret"},
+	}
+}
+
+// NewClientLogTransport returns an http.RoundTripper which logs the outbound
+// requests done by base, http.DefaultTransport if nil: method, URL, status,
+// latency and bytes, with the fields carried by the request context. Failed
+// calls are logged with error level. The requests and the responses are
+// passed as they are, a retrying base logs the call once.
+func NewClientLogTransport(base http.RoundTripper, opts ClientLogOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.Level == LevelTrace {
+		opts.Level = LevelInfo
+	}
+	return &clientLogTransport{base: base, opts: opts}
+}
+
+type clientLogTransport struct {
+	base http.RoundTripper
+	opts ClientLogOptions
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *clientLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := Fields{}
+	for k, v := range FieldsFromContext(req.Context()) {
+		fields[k] = v
+	}
+	if t.opts.MaxBodyBytes > 0 && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(body, t.opts.MaxBodyBytes))
+			body.Close()
+			fields["request_body"] = string(data)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	u := *req.URL
+	u.RawQuery = redactQuery(u.RawQuery, t.opts.RedactQuery)
+	u.User = nil
+	fields["method"] = req.Method
+	fields["url"] = u.String()
+	fields["duration"] = elapsed
+	if req.ContentLength > 0 {
+		fields["request_bytes"] = req.ContentLength
+	}
+
+	conf := loadConf()
+	logLevel := int(t.opts.Level)
+	if t.opts.SlowThreshold > 0 && elapsed >= t.opts.SlowThreshold &&
+		(!conf.validLevel(logLevel) || conf.levels[logLevel].rank < conf.levels[logLevelWarn].rank) {
+		logLevel = logLevelWarn
+		fields["slow"] = true
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		logDepth(logLevelError, 0, fields, "HTTP client %s %s failed", []interface{}{req.Method, u.String()})
+		return nil, err
+	}
+
+	fields["status"] = resp.StatusCode
+	if resp.ContentLength >= 0 {
+		fields["response_bytes"] = resp.ContentLength
+	}
+	if !conf.validLevel(logLevel) || !conf.levelEnabled(logLevel) {
+		return resp, nil
+	}
+	args := []interface{}{req.Method, u.String(), resp.StatusCode}
+	// a 101 Switching Protocols body is the connection, it is not wrapped
+	if t.opts.MaxBodyBytes > 0 && resp.Body != nil && resp.Body != http.NoBody && resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body = &capturedBody{
+			ReadCloser: resp.Body,
+			limit:      t.opts.MaxBodyBytes,
+			done: func(data []byte) {
+				fields["response_body"] = string(data)
+				logDepth(logLevel, 0, fields, "HTTP client %s %s %d", args)
+			},
+		}
+		return resp, nil
+	}
+	logDepth(logLevel, 0, fields, "HTTP client %s %s %d", args)
+	return resp, nil
+}
+
+// capturedBody keeps the first limit bytes read from a response body and
+// passes them to done once the body is read to the end or closed.
+type capturedBody struct {
+	io.ReadCloser
+	limit int64
+	done  func(data []byte)
+
+	lock   sync.Mutex
+	data   []byte
+	closed bool
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.lock.Lock()
+	if rest := b.limit - int64(len(b.data)); rest > 0 {
+		if int64(n) < rest {
+			rest = int64(n)
+		}
+		b.data = append(b.data, p[:rest]...)
+	}
+	b.lock.Unlock()
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+// finish calls done the first time it is called.
+func (b *capturedBody) finish() {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return
+	}
+	b.closed = true
+	data := b.data
+	b.lock.Unlock()
+	b.done(data)
+}
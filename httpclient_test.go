@@ -0,0 +1,189 @@
+package log
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientLogTransport_LogsCall(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("cli", "cli")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("pong"))
+	}))
+	defer srv.Close()
+
+	obs := NewObserver()
+	defer AddSink(obs)()
+
+	opts := DefaultClientLogOptions()
+	opts.MaxBodyBytes = 2
+	client := &http.Client{Transport: NewClientLogTransport(nil, opts)}
+	req, _ := http.NewRequestWithContext(ContextWithRequestID(context.Background(), "r-1"), http.MethodGet, srv.URL+"/ping?token=abc&q=1", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if obs.Len() != 0 {
+		t.Fatalf("expected the call logged once its body is read, got %v", obs.All())
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the body failed: %v", err)
+	}
+	resp.Body.Close()
+	if obs.Len() != 1 {
+		t.Fatalf("expected the call logged once, got %d entries", obs.Len())
+	}
+
+	out := readLogFile(t, dir, "cli.info")
+	for _, want := range []string{"HTTP client GET", "token=REDACTED&q=1", "status=200", "request_id=r-1", "response_bytes=4", "response_body=po"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "abc") {
+		t.Fatalf("expected query token redacted:\n%s", out)
+	}
+	if string(body) != "pong" {
+		t.Fatalf("expected response body preserved, got %q", body)
+	}
+}
+
+func TestClientLogTransport_SlowWithoutRetries(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("cli", "cli")
+	SetLogThrough(false)
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	opts := DefaultClientLogOptions()
+	opts.SlowThreshold = time.Nanosecond
+	client := &http.Client{Transport: NewClientLogTransport(nil, opts)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected the 503 passed after a single call, got status %d after %d calls", resp.StatusCode, calls)
+	}
+	out := readLogFile(t, dir, "cli.warn")
+	if !strings.Contains(out, "status=503") || !strings.Contains(out, "slow=true") || strings.Contains(out, "retries") {
+		t.Fatalf("expected the slow call in warn log, got:\n%s", out)
+	}
+}
+
+type switchingTransport struct {
+	body io.ReadWriteCloser
+}
+
+func (t switchingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusSwitchingProtocols, Body: t.body, ContentLength: -1, Request: req}, nil
+}
+
+type nopConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (nopConn) Close() error { return nil }
+
+func TestClientLogTransport_SwitchingProtocols(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	obs := NewObserver()
+	defer AddSink(obs)()
+
+	conn := nopConn{strings.NewReader("frames"), io.Discard}
+	opts := DefaultClientLogOptions()
+	opts.MaxBodyBytes = 4
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/ws", nil)
+	resp, err := NewClientLogTransport(switchingTransport{conn}, opts).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+	if _, ok := resp.Body.(io.ReadWriteCloser); !ok || resp.Body != io.ReadCloser(conn) {
+		t.Fatalf("expected the 101 body passed as it is, got %T", resp.Body)
+	}
+	if obs.FilterField("status", http.StatusSwitchingProtocols).Len() != 1 {
+		t.Fatalf("expected the upgrade logged at once, got %v", obs.All())
+	}
+}
+
+func TestClientLogTransport_LogsErrors(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("cli", "cli")
+
+	client := &http.Client{Transport: NewClientLogTransport(nil, DefaultClientLogOptions())}
+	if _, err := client.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatalf("expected request to fail")
+	}
+	if out := readLogFile(t, dir, "cli.error"); !strings.Contains(out, "failed") || !strings.Contains(out, "error=") {
+		t.Fatalf("expected failed call in error log, got:\n%s", out)
+	}
+}
+
+func TestClientLogTransport_LevelDefaultsAndRank(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("cli", "cli")
+	SetLogThrough(false)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	// the zero options log with info level
+	client := &http.Client{Transport: NewClientLogTransport(nil, ClientLogOptions{})}
+	resp, err := client.Get(srv.URL + "/zero")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if out := readLogFile(t, dir, "cli.info"); !strings.Contains(out, "/zero") {
+		t.Fatalf("expected the call in info log, got:\n%s", out)
+	}
+
+	// a slow call of a muted debug level is escalated to warn level
+	client = &http.Client{Transport: NewClientLogTransport(nil, ClientLogOptions{Level: LevelDebug, SlowThreshold: time.Nanosecond})}
+	resp, err = client.Get(srv.URL + "/slow")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if out := readLogFile(t, dir, "cli.warn"); !strings.Contains(out, "/slow") || !strings.Contains(out, "slow=true") {
+		t.Fatalf("expected the slow call in warn log, got:\n%s", out)
+	}
+}
@@ -0,0 +1,303 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the values of redacted headers and query parameters.
+const Redacted = "REDACTED"
+
+// HTTPMetaOptions configures the metadata collected by EnrichHTTPMetaWithOptions.
+type HTTPMetaOptions struct {
+	// Headers are the request headers copied into the metadata.
+	Headers []string
+	// RedactHeaders are the headers whose values are replaced with Redacted.
+	RedactHeaders []string
+	// RedactQuery are the query parameters whose values are replaced with
+	// Redacted.
+	RedactQuery []string
+	// TrustedProxies are the IPs or CIDRs of the proxies whose X-Forwarded-For
+	// and X-Real-IP headers are honored when extracting the client IP.
+	TrustedProxies []string
+	// StackThreshold is the minimal status a stack is collected for, zero
+	// disables stacks.
+	StackThreshold int
+	// StackDepth is the number of stack frames collected.
+	StackDepth int
+	// MaxBodyBytes is the number of request body bytes collected, zero
+	// disables body capture. The request body is restored for later readers.
+	MaxBodyBytes int64
+}
+
+// DefaultHTTPMetaOptions returns the options used by EnrichHTTPMeta.
+func DefaultHTTPMetaOptions() HTTPMetaOptions {
+	return HTTPMetaOptions{
+		Headers:        []string{"X-Request-Id", "UserName", "Application-Version", "User-Agent"},
+		RedactHeaders:  []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"},
+		StackThreshold: http.StatusInternalServerError,
+		StackDepth:     6,
+	}
+}
+
+// EnrichHTTPMeta populates and returns a metadata map with useful diagnostic
+// information for HTTP error logging. It mirrors the enrichment previously
+// performed in controller.jsonErrorResponseWithMeta so callers can reuse the
+// same behavior from the central logger package.
+//
+// Provided fields:
+//   - origin, originFile, originLine (caller info)
+//   - method, path, query (from *http.Request)
+//   - clientIP (from the remote address of *http.Request)
+//   - selected headers: X-Request-Id, UserName, Application-Version, User-Agent
+//   - X-Request-Id from the request context when not sent as a header
+//   - timestamp (human-readable), hostname
+//   - stack (short stack trace) when status >= 500
+func EnrichHTTPMeta(status int, req *http.Request, meta map[string]interface{}, callerSkip int) map[string]interface{} {
+	return enrichHTTPMeta(status, req, meta, callerSkip+1, DefaultHTTPMetaOptions())
+}
+
+// EnrichHTTPMetaWithOptions works like EnrichHTTPMeta, but the headers, the
+// redactions, the client IP extraction, the stack and the body capture are
+// configured by opts.
+//
+// Additional fields:
+//   - body, bodyTruncated when opts.MaxBodyBytes > 0
+func EnrichHTTPMetaWithOptions(status int, req *http.Request, meta map[string]interface{}, callerSkip int, opts HTTPMetaOptions) map[string]interface{} {
+	return enrichHTTPMeta(status, req, meta, callerSkip+1, opts)
+}
+
+func enrichHTTPMeta(status int, req *http.Request, meta map[string]interface{}, callerSkip int, opts HTTPMetaOptions) map[string]interface{} {
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+
+	// origin information. callerSkip allows the caller to control how many
+	// stack frames to skip so origin points to the real caller (for example,
+	// when called from a wrapper in another package).
+	if _, ok := meta["origin"]; !ok {
+		if pc, file, line, okc := runtime.Caller(callerSkip); okc {
+			if fn := runtime.FuncForPC(pc); fn != nil {
+				meta["origin"] = path.Base(fn.Name())
+			}
+			if _, okf := meta["originFile"]; !okf {
+				meta["originFile"] = file
+			}
+			if _, okl := meta["originLine"]; !okl {
+				meta["originLine"] = line
+			}
+		}
+	}
+
+	// request information
+	if req != nil {
+		if _, ok := meta["method"]; !ok {
+			meta["method"] = req.Method
+		}
+		if _, ok := meta["path"]; !ok {
+			meta["path"] = req.URL.Path
+		}
+		if _, ok := meta["query"]; !ok {
+			meta["query"] = redactQuery(req.URL.RawQuery, opts.RedactQuery)
+		}
+		if _, ok := meta["clientIP"]; !ok {
+			if ip := clientIP(req, opts.TrustedProxies); ip != "" {
+				meta["clientIP"] = ip
+			}
+		}
+		for _, h := range opts.Headers {
+			if _, ok := meta[h]; !ok {
+				if v := req.Header.Get(h); v != "" {
+					if containsFold(opts.RedactHeaders, h) {
+						v = Redacted
+					}
+					meta[h] = v
+				}
+			}
+		}
+		// request ID stored in the context by the RequestID middleware
+		if _, ok := meta[DefRequestIDHeader]; !ok {
+			if id := RequestIDFromContext(req.Context()); id != "" {
+				meta[DefRequestIDHeader] = id
+			}
+		}
+		if _, ok := meta["body"]; !ok && opts.MaxBodyBytes > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, truncated := captureBody(req, opts.MaxBodyBytes)
+			meta["body"] = body
+			if truncated {
+				meta["bodyTruncated"] = true
+			}
+		}
+	}
+
+	// timestamp and hostname
+	if _, ok := meta["timestamp"]; !ok {
+		meta["timestamp"] = time.Now().Format("2006-01-02 15:04:05 MST")
+	}
+	if _, ok := meta["hostname"]; !ok {
+		if h, err := os.Hostname(); err == nil {
+			meta["hostname"] = h
+		}
+	}
+
+	// stack for server errors
+	if opts.StackThreshold > 0 && status >= opts.StackThreshold && opts.StackDepth > 0 {
+		if _, ok := meta["stack"]; !ok {
+			pcs := make([]uintptr, opts.StackDepth)
+			n := runtime.Callers(callerSkip+1, pcs)
+			frames := runtime.CallersFrames(pcs[:n])
+			var b strings.Builder
+			for {
+				f, more := frames.Next()
+				fmt.Fprintf(&b, "%s:%d %s\n", f.File, f.Line, path.Base(f.Function))
+				if !more {
+					break
+				}
+			}
+			meta["stack"] = b.String()
+		}
+	}
+
+	return meta
+}
+
+// LogHTTPError enriches meta like EnrichHTTPMeta and writes it as the fields
+// of a single log entry, together with the status and err. Statuses 5xx are
+// logged with error level, 4xx with warn level and others with info level.
+// The fields carried by the request context are written as well.
+func LogHTTPError(status int, req *http.Request, err error, meta map[string]interface{}) {
+	logLevel := logLevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		logLevel = logLevelError
+	case status >= http.StatusBadRequest:
+		logLevel = logLevelWarn
+	}
+
+	fields := make(Fields, len(meta)+2)
+	for k, v := range meta {
+		fields[k] = v
+	}
+	fields = enrichHTTPMeta(status, req, fields, 2, DefaultHTTPMetaOptions())
+	fields["status"] = status
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	if req != nil {
+		for k, v := range FieldsFromContext(req.Context()) {
+			if _, ok := fields[k]; !ok {
+				fields[k] = v
+			}
+		}
+	}
+
+	logDepth(logLevel, 0, fields, "HTTP %d %s", []interface{}{status, http.StatusText(status)})
+}
+
+// redactQuery replaces the values of the named parameters in a raw query,
+// keeping the order of the parameters. The names are compared with the
+// decoded keys, so an escaped key, e.g. p%61ssword, is redacted too.
+func redactQuery(rawQuery string, names []string) string {
+	if rawQuery == "" || len(names) == 0 {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, p := range params {
+		key := p
+		if j := strings.IndexByte(p, '='); j >= 0 {
+			key = p[:j]
+		}
+		name := key
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			name = unescaped
+		}
+		if containsFold(names, name) {
+			params[i] = key + "=" + Redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// clientIP returns the IP of the client. X-Forwarded-For and X-Real-IP are
+// only honored when the request comes from one of the trusted proxies.
+func clientIP(req *http.Request, trustedProxies []string) string {
+	remote := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if len(trustedProxies) == 0 || !isTrustedProxy(remote, trustedProxies) {
+		return remote
+	}
+
+	// walk X-Forwarded-For from the nearest hop, the first untrusted hop is
+	// the client
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(hop, trustedProxies) {
+			return hop
+		}
+		remote = hop
+	}
+	if ip := strings.TrimSpace(req.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return remote
+}
+
+func isTrustedProxy(addr string, trustedProxies []string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, p := range trustedProxies {
+		if strings.Contains(p, "/") {
+			if _, ipnet, err := net.ParseCIDR(p); err == nil && ipnet.Contains(ip) {
+				return true
+			}
+		} else if pip := net.ParseIP(p); pip != nil && pip.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureBody reads up to max bytes of the request body and restores the body
+// so it can be read again.
+func captureBody(req *http.Request, max int64) (string, bool) {
+	data, err := io.ReadAll(io.LimitReader(req.Body, max+1))
+	truncated := int64(len(data)) > max
+	rest := req.Body
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), rest), rest}
+	if err != nil {
+		return "", false
+	}
+	if truncated {
+		data = data[:max]
+	}
+	return string(data), truncated
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package log
+
+import "context"
+
+// NewSinkLogger returns a Logger writing to s only, instead of the logfiles,
+// the console and the sinks added with AddSink.
+func NewSinkLogger(s Sink) Logger {
+	return Logger{sink: s}
+}
+
+// Trace logs down a log with trace level, if trace logs are turned on.
+func (l Logger) Trace(format string, args ...interface{}) {
+	l.logf(logLevelTrace, 0, nil, format, args)
+}
+
+// Info logs down a log with info level.
+func (l Logger) Info(format string, args ...interface{}) {
+	l.logf(logLevelInfo, 0, nil, format, args)
+}
+
+// Update logs down a log with update level.
+func (l Logger) Update(format string, args ...interface{}) {
+	l.logf(logLevelUpdate, 0, nil, format, args)
+}
+
+// Warn logs down a log with warning level.
+func (l Logger) Warn(format string, args ...interface{}) {
+	l.logf(logLevelWarn, 0, nil, format, args)
+}
+
+// Error logs down a log with error level.
+func (l Logger) Error(format string, args ...interface{}) {
+	l.logf(logLevelError, 0, nil, format, args)
+}
+
+// Panic logs down a log with panic level.
+func (l Logger) Panic(format string, args ...interface{}) {
+	l.logf(logLevelPanic, 0, nil, format, args)
+}
+
+// Abort logs down a log with abort level.
+func (l Logger) Abort(format string, args ...interface{}) {
+	l.logf(logLevelAbort, 0, nil, format, args)
+}
+
+// Query logs down a log with query level.
+func (l Logger) Query(format string, args ...interface{}) {
+	l.logf(logLevelQuery, 0, nil, format, args)
+}
+
+// Debug logs down a log with debug level, if debug logs are turned on.
+func (l Logger) Debug(format string, args ...interface{}) {
+	l.logf(logLevelDebug, 0, nil, format, args)
+}
+
+// Log logs down a log with the given level, see Log.
+func (l Logger) Log(level Level, format string, args ...interface{}) {
+	if level.valid() {
+		l.logf(int(level), 0, nil, format, args)
+	}
+}
+
+// logf works like logDepth, but writes to the sink of l if any.
+func (l Logger) logf(logLevel, depth int, fields Fields, format string, args []interface{}) {
+	conf := loadConf()
+	if !conf.verbose(logLevel, depth+1) {
+		return
+	}
+	if l.sink == nil {
+		logDepth(logLevel, depth+1, fields, format, args)
+		return
+	}
+	if conf.isEnabled() {
+		fields = conf.withBacktrace(depth+1, fields)
+		l.sink.Log(newEntry(logLevel, 3+depth, conf.now(), fields, format, args))
+	}
+}
+
+const loggerKey contextKey = fieldsKey + 1
+
+// ContextWithLogger returns a copy of ctx carrying l. The context-aware log
+// functions, e.g. InfoContext, write to l instead of the logfiles, so code
+// under test logs to the Logger of its test.
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// LoggerFromContext returns the Logger carried by ctx, or the zero Logger
+// writing to the logfiles.
+func LoggerFromContext(ctx context.Context) Logger {
+	if ctx == nil {
+		return Logger{}
+	}
+	l, _ := ctx.Value(loggerKey).(Logger)
+	return l
+}
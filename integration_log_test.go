@@ -5,7 +5,6 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 )
 
 // TestIntegration_LogFilesCreated initializes the logger to a temp directory,
@@ -28,29 +27,29 @@ func TestIntegration_LogFilesCreated(t *testing.T) {
 	SetFilenamePrefix("testprefix", "testprefix")
 	SetLogToConsole(false)
 
+	obs := NewObserver()
+	defer AddSink(obs)()
+
 	// Write some logs
 	Info("integration test starting")
 	for i := 0; i < 50; i++ {
 		Info("msg %d", i)
 		Error("err %d", i)
 	}
+	if n := obs.FilterLevel(LevelError).Len(); n != 50 {
+		t.Fatalf("expected 50 error entries, got %d", n)
+	}
 
-	// Check that at least one .log file exists in the directory (poll briefly
-	// to avoid flakes due to async writes).
+	// Logs are written synchronously, so the files exist once the calls return.
 	foundLog := false
-	for attempt := 0; attempt < 40 && !foundLog; attempt++ {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			t.Fatalf("ReadDir failed: %v", err)
-		}
-		for _, e := range entries {
-			if !e.IsDir() && strings.HasSuffix(e.Name(), ".log") {
-				foundLog = true
-				break
-			}
-		}
-		if !foundLog {
-			time.Sleep(50 * time.Millisecond)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".log") {
+			foundLog = true
+			break
 		}
 	}
 	if !foundLog {
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is a log severity level. Each level is written to its own logfile.
+type Level int
+
+// log levels which can be passed to the functions accepting a Level
+const (
+	LevelTrace  Level = logLevelTrace
+	LevelInfo   Level = logLevelInfo
+	LevelWarn   Level = logLevelWarn
+	LevelError  Level = logLevelError
+	LevelUpdate Level = logLevelUpdate
+	LevelPanic  Level = logLevelPanic
+	LevelAbort  Level = logLevelAbort
+	LevelQuery  Level = logLevelQuery
+	LevelDebug  Level = logLevelDebug
+)
+
+// levelDef describes a level.
+type levelDef struct {
+	name string // used in logfile and symlink names
+	char byte   // first character of the text lines
+	rank int    // severity, higher is more severe
+}
+
+// gDefaultLevels are the built-in levels, indexed by their Level.
+var gDefaultLevels = [logLevelMax]levelDef{
+	logLevelTrace:  {"trace", 'T', 0},
+	logLevelInfo:   {"info", 'I', 30},
+	logLevelWarn:   {"warn", 'W', 50},
+	logLevelError:  {"error", 'E', 60},
+	logLevelUpdate: {"update", 'U', 40},
+	logLevelPanic:  {"panic", 'P', 70},
+	logLevelAbort:  {"abort", 'A', 80},
+	logLevelQuery:  {"query", 'Q', 20},
+	logLevelDebug:  {"debug", 'D', 10},
+}
+
+// RegisterLevel registers a level written to its own logfile and symlink,
+// e.g. audit, security or access. The name is used in the logfile names, char
+// starts the text lines and rank is the severity, compared with the Rank of
+// the other levels. Registering a level again with the same char and rank
+// returns it. The level is not written through to other logfiles unless set
+// with SetThrough.
+//
+// The levels registered are written with Log, LogContext or Logger.Log.
+func RegisterLevel(name string, char byte, rank int) (Level, error) {
+	if err := checkLevelName(name, char); err != nil {
+		return 0, err
+	}
+
+	var l Level
+	var err error
+	updateConf(func(conf *config) {
+		for i, d := range conf.levels {
+			if strings.EqualFold(d.name, name) {
+				l = Level(i)
+				if d.char != char || d.rank != rank {
+					err = fmt.Errorf("log level %q already registered", name)
+				}
+				return
+			}
+		}
+
+		l = Level(len(conf.levels))
+		conf.levels = append(conf.levels[:len(conf.levels):len(conf.levels)], levelDef{name: name, char: char, rank: rank})
+		conf.loggers = append(conf.loggers[:len(conf.loggers):len(conf.loggers)], &logger{level: int(l)})
+		conf.through = append(conf.through[:len(conf.through):len(conf.through)], conf.defaultThrough(int(l)))
+		conf.setFilenamePrefix(conf.filenamePrefix, conf.symlinkPrefix)
+	})
+	return l, err
+}
+
+// RenameLevel changes the name and the char of the level l. The symlink with
+// the previous name is removed, the next log of the level opens a logfile with
+// the new name and links the symlink with the new name to it. The logfiles
+// with the previous name are kept until purged.
+func RenameLevel(l Level, name string, char byte) error {
+	if err := checkLevelName(name, char); err != nil {
+		return err
+	}
+
+	var err error
+	var oldSymlink string
+	conf := updateConf(func(conf *config) {
+		if int(l) < 0 || int(l) >= len(conf.levels) {
+			err = fmt.Errorf("unknown log level %d", l)
+			return
+		}
+		for i, d := range conf.levels {
+			if i != int(l) && strings.EqualFold(d.name, name) {
+				err = fmt.Errorf("log level %q already registered", name)
+				return
+			}
+		}
+
+		oldSymlink = conf.symlink(int(l))
+		levels := append([]levelDef(nil), conf.levels...)
+		levels[l].name = name
+		levels[l].char = char
+		conf.levels = levels
+		conf.setFilenamePrefix(conf.filenamePrefix, conf.symlinkPrefix)
+	})
+	if err != nil || oldSymlink == conf.symlink(int(l)) {
+		return err
+	}
+
+	// under the lock, so a log with the previous name does not link it again
+	lg := conf.loggers[l]
+	lg.lock.Lock()
+	defer lg.lock.Unlock()
+	_ = conf.filesystem().Remove(oldSymlink)
+	return nil
+}
+
+// checkLevelName checks the name is usable in the logfile names.
+func checkLevelName(name string, char byte) error {
+	if name == "" {
+		return fmt.Errorf("empty log level name")
+	}
+	if strings.EqualFold(name, allFileName) {
+		return fmt.Errorf("log level name %q is reserved for the combined logfile", name)
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return fmt.Errorf("invalid log level name %q: only letters, digits, _ and - are allowed", name)
+		}
+	}
+	if char <= ' ' || char > '~' {
+		return fmt.Errorf("invalid log level char %q", char)
+	}
+	return nil
+}
+
+// String returns the name of the level as used in logfile names.
+func (l Level) String() string {
+	conf := loadConf()
+	if !conf.validLevel(int(l)) {
+		return "unknown"
+	}
+	return conf.levels[l].name
+}
+
+// Char returns the character starting the text lines of the level, '?' for
+// an unknown level.
+func (l Level) Char() byte {
+	conf := loadConf()
+	if !conf.validLevel(int(l)) {
+		return '?'
+	}
+	return conf.levels[l].char
+}
+
+// Rank returns the severity of the level, higher is more severe.
+func (l Level) Rank() int {
+	conf := loadConf()
+	if !conf.validLevel(int(l)) {
+		return 0
+	}
+	return conf.levels[l].rank
+}
+
+// ParseLevel returns the level with the given name, e.g. info or warn.
+func ParseLevel(name string) (Level, error) {
+	for i, d := range loadConf().levels {
+		if strings.EqualFold(d.name, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+func (l Level) valid() bool {
+	return loadConf().validLevel(int(l))
+}
+
+func (conf *config) validLevel(logLevel int) bool {
+	return logLevel >= 0 && logLevel < len(conf.levels)
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegisterLevel(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+	obs := NewObserver()
+	defer AddSink(obs)()
+
+	audit, err := RegisterLevel("audit", 'S', 65)
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+	if again, err := RegisterLevel("audit", 'S', 65); err != nil || again != audit {
+		t.Fatalf("expected the level registered again, got %v %v", again, err)
+	}
+	if audit.String() != "audit" || audit.Rank() != 65 {
+		t.Fatalf("unexpected level %q rank %d", audit, audit.Rank())
+	}
+	if l, err := ParseLevel("AUDIT"); err != nil || l != audit {
+		t.Fatalf("ParseLevel failed: %v %v", l, err)
+	}
+
+	Log(audit, "user %s logged in", "joe")
+	LogContext(context.Background(), audit, "from context")
+	Log(Level(100), "dropped")
+
+	data := readLogFile(t, dir, "x.audit")
+	if !strings.HasPrefix(data, "S") || !strings.Contains(data, "user joe logged in") || !strings.Contains(data, "from context") {
+		t.Fatalf("unexpected audit logfile:\n%s", data)
+	}
+	// registered levels are not written through
+	if _, err := os.Lstat(filepath.Join(dir, "x.info")); !os.IsNotExist(err) {
+		t.Fatalf("expected no info logfile, got %v", err)
+	}
+	if n := obs.FilterLevel(audit).Len(); n != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", n)
+	}
+	if n := obs.Len(); n != 2 {
+		t.Fatalf("expected the log with an unknown level dropped, got %d entries", n)
+	}
+
+	for _, c := range []struct {
+		name string
+		char byte
+		rank int
+	}{
+		{"audit", 'A', 65},
+		{"", 'X', 1},
+		{"a/b", 'X', 1},
+		{"ok", ' ', 1},
+	} {
+		if _, err := RegisterLevel(c.name, c.char, c.rank); err == nil {
+			t.Fatalf("expected an error registering %q %q", c.name, c.char)
+		}
+	}
+}
+
+func TestRenameLevel(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+
+	Warn("before")
+	if err := RenameLevel(LevelWarn, "warning", 'w'); err != nil {
+		t.Fatalf("RenameLevel failed: %v", err)
+	}
+	Warn("after")
+
+	if LevelWarn.String() != "warning" {
+		t.Fatalf("unexpected name %q", LevelWarn)
+	}
+	if l, err := ParseLevel("warning"); err != nil || l != LevelWarn {
+		t.Fatalf("ParseLevel failed: %v %v", l, err)
+	}
+	if _, err := ParseLevel("warn"); err == nil {
+		t.Fatalf("expected the old name unknown")
+	}
+
+	data := readLogFile(t, dir, "x.warning")
+	if strings.Contains(data, "before") || !strings.HasPrefix(data, "w") || !strings.Contains(data, "after") {
+		t.Fatalf("unexpected renamed logfile:\n%s", data)
+	}
+	target, err := os.Readlink(filepath.Join(dir, "x.warning"))
+	if err != nil {
+		t.Fatalf("Readlink failed: %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(target), "x.warning_") {
+		t.Fatalf("unexpected symlink target %q", target)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "x.warn")); !os.IsNotExist(err) {
+		t.Fatalf("expected the previous symlink removed, got %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "x.warn_*.log"))
+	if len(files) != 1 {
+		t.Fatalf("expected the previous logfile kept, got %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); !strings.Contains(string(data), "before") {
+		t.Fatalf("unexpected previous logfile:\n%s", data)
+	}
+
+	if err := RenameLevel(LevelWarn, "error", 'E'); err == nil {
+		t.Fatalf("expected an error renaming to a used name")
+	}
+	if err := RenameLevel(Level(100), "other", 'O'); err == nil {
+		t.Fatalf("expected an error renaming an unknown level")
+	}
+}
+
+func TestLoggerLog(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+	obs := NewObserver()
+	l := NewSinkLogger(obs)
+
+	access, err := RegisterLevel("access", 'X', 35)
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+	l.Log(access, "GET /")
+	l.Log(LevelDebug, "muted")
+	l.Log(Level(-1), "dropped")
+	if obs.Len() != 1 || obs.FilterLevel(access).Len() != 1 {
+		t.Fatalf("expected a single access entry, got %d", obs.Len())
+	}
+}
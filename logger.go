@@ -24,7 +24,6 @@ package log
 
 import (
 	"fmt"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -46,7 +45,7 @@ const (
 	logLevelQuery
 	logLevelDebug
 
-	logLevelMax
+	logLevelMax // number of the built-in levels, see RegisterLevel
 )
 
 // log flags
@@ -57,17 +56,9 @@ const (
 	flagLogFilenameLineNum
 	flagLogToConsole
 	flagLogDebug
-)
-
-// log info
-var (
-	infoUserName string
-	infoHostName string
-	// gMaxFileSizeBytes controls the maximum logfile size in bytes before
-	// rotation. Tests may override this by providing a test-only helper, but
-	// provide a sensible zero-value default here so normal builds compile and
-	// use day-based rotation only when not configured in tests.
-	gMaxFileSizeBytes int64
+	flagStdLogTags
+	flagCopyTruncate
+	flagLogAll
 )
 
 // const strings
@@ -76,8 +67,6 @@ const (
 	DefFilenamePrefix = "%P.%H.%U"
 	// Default filename prefix for symlinks to logfiles
 	DefSymlinkPrefix = "%P.%U"
-
-	logLevelChar = "TIWEUPAQD"
 )
 
 // Init must be called first, otherwise this logger will not function properly!
@@ -86,74 +75,136 @@ const (
 //	maxdays: Maximum days to keep logs.
 //	logTrace: If set to false, `logger.Trace("xxxx")` will be mute.
 func Init(logpath string, maxdays int, logTrace bool) error {
-	err := os.MkdirAll(logpath, 0755)
+	err := loadConf().filesystem().MkdirAll(logpath, 0755)
 	if err != nil {
 		return err
 	}
 
-	infoHostName, err = os.Hostname()
+	hostName, err := os.Hostname()
 	if err != nil {
 		return err
 	}
 
-	gConf.logPath = logpath + "/"
-	gConf.maxdays = maxdays
-	gConf.setFlags(flagLogTrace, logTrace)
+	updateConf(func(conf *config) {
+		conf.logPath = logpath + "/"
+		conf.maxdays = maxdays
+		conf.hostName = hostName
+		conf.setFlags(flagLogTrace, logTrace)
+		conf.setFilenamePrefix(DefFilenamePrefix, DefSymlinkPrefix)
+	})
+	closeFiles()
 
-	SetFilenamePrefix(DefFilenamePrefix, DefSymlinkPrefix)
+	return nil
+}
+
+// SetLogPath changes the directory the logfiles are written to. The open
+// logfiles are closed, the next log of every level opens a logfile under the
+// new directory.
+func SetLogPath(logpath string) error {
+	err := loadConf().filesystem().MkdirAll(logpath, 0755)
+	if err != nil {
+		return err
+	}
+
+	updateConf(func(conf *config) {
+		conf.logPath = logpath + "/"
+		conf.setFilenamePrefix(conf.filenamePrefix, conf.symlinkPrefix)
+	})
+	closeFiles()
 
 	return nil
 }
 
+// GetLogPath returns the directory the logfiles are written to.
+func GetLogPath() string {
+	return loadConf().logPath
+}
+
+// Reopen closes the open logfiles and opens them again by name. It is meant
+// for external rotation, e.g. by logrotate with the create mode, which moves
+// the logfiles away, then asks the program to write to new ones.
+// The first error, if any, is returned.
+func Reopen() error {
+	var first error
+	for _, l := range loadConf().files() {
+		if err := l.reopen(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Rotate opens new logfiles for all the levels having an open logfile, as
+// done on day change or when a logfile exceeds the size limit.
+// The first error, if any, is returned.
+func Rotate() error {
+	var first error
+	for _, l := range loadConf().files() {
+		if err := l.rotate(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// closeFiles closes the open logfiles of all the levels.
+func closeFiles() {
+	for _, l := range loadConf().files() {
+		l.close()
+	}
+}
+
 // SetLogTrace sets to write trace log file
 func SetLogTrace(on bool) {
-	gConf.setFlags(flagLogTrace, on)
+	setFlags(flagLogTrace, on)
 }
 
 // SetLogDebug sets to write trace log file
 func SetLogDebug(on bool) {
-	gConf.setFlags(flagLogDebug, on)
+	setFlags(flagLogDebug, on)
 }
 
 // SetLogThrough sets whether to write log to all the logfiles with less severe log level.
 // By default, logthrough is turn on. You can turn it off for better performance.
+// The logfiles each level is written through to are set with SetThrough.
 func SetLogThrough(on bool) {
-	gConf.setFlags(flagLogThrough, on)
+	setFlags(flagLogThrough, on)
 }
 
 // SetLogFunctionName sets whether to log down the function name where the log takes place.
 // By default, function name is not logged down for better performance.
 func SetLogFunctionName(on bool) {
-	gConf.setFlags(flagLogFuncName, on)
+	setFlags(flagLogFuncName, on)
 }
 
 // SetLogFilenameLineNum sets whether to log down the filename and line number where the log takes place.
 // By default, filename and line number are logged down. You can turn it off for better performance.
 func SetLogFilenameLineNum(on bool) {
-	gConf.setFlags(flagLogFilenameLineNum, on)
+	setFlags(flagLogFilenameLineNum, on)
 }
 
-// SetLogToConsole sets whether to output logs to the console.
+// SetLogToConsole sets whether to output logs to the console, as set with
+// SetConsoleOptions.
 // By default, logs are not output to the console.
 func SetLogToConsole(on bool) {
-	gConf.setFlags(flagLogToConsole, on)
+	setFlags(flagLogToConsole, on)
 }
 
 // SetLogUserName sets user name to write to log.
 // By default, empty
 func SetLogUserName(name string) {
-	infoUserName = name
+	updateConf(func(conf *config) { conf.userName = name })
 }
 
 // SetLogDisable logging
 // By default, logs are enabled
 func SetLogDisable() {
-	gConf.enabled = false
+	updateConf(func(conf *config) { conf.enabled = false })
 }
 
 // SetLogEnable set logging enabled
 func SetLogEnable() {
-	gConf.enabled = true
+	updateConf(func(conf *config) { conf.enabled = true })
 }
 
 // SetFilenamePrefix sets filename prefix for the logfiles and symlinks of the logfiles.
@@ -169,13 +220,14 @@ func SetLogEnable() {
 // The default prefix for a log filename is logger.DefFilenamePrefix ("%P.%H.%U").
 // The default prefix for a symlink is logger.DefSymlinkPrefix ("%P.%U").
 func SetFilenamePrefix(logfilenamePrefix, symlinkPrefix string) {
-	gConf.setFilenamePrefix(logfilenamePrefix, symlinkPrefix)
+	updateConf(func(conf *config) { conf.setFilenamePrefix(logfilenamePrefix, symlinkPrefix) })
 }
 
 // Trace logs down a log with trace level.
-// If parameter logTrace of logger.Init() is set to be false, no trace logs will be logged down.
+// If parameter logTrace of logger.Init() is set to be false, no trace logs will be logged down,
+// unless turned on for the caller with SetVModule.
 func Trace(format string, args ...interface{}) {
-	if gConf.logTrace() {
+	if loadConf().verbose(logLevelTrace, 0) {
 		log(logLevelTrace, format, args)
 	}
 }
@@ -220,24 +272,38 @@ func Query(format string, args ...interface{}) {
 	log(logLevelQuery, format, args)
 }
 
-// Debug logs down a log with debug level
+// Debug logs down a log with debug level, if debug logs are turned on for the caller.
 func Debug(format string, args ...interface{}) {
-	if gConf.logDebug() {
+	if loadConf().verbose(logLevelDebug, 0) {
 		log(logLevelDebug, format, args)
 	}
 }
 
-type Logger struct{}
+// Log logs down a log with the given level, e.g. one registered with
+// RegisterLevel. Trace and debug logs are written if turned on for the caller,
+// logs with an unknown level are dropped.
+func Log(level Level, format string, args ...interface{}) {
+	if conf := loadConf(); conf.validLevel(int(level)) && conf.verbose(int(level), 0) {
+		log(int(level), format, args)
+	}
+}
+
+// Logger writes logs like the package level functions. The zero value writes
+// to the logfiles, a Logger returned by NewSinkLogger or
+// logtest.NewTestLogger writes to its sink only.
+type Logger struct {
+	sink Sink
+}
 
 func (l Logger) Println(v ...interface{}) {
 	// Print the provided values as a single string. Use Trace-level logging to
 	// remain consistent with the original intent, but provide a format so the
 	// values are not dropped when format is empty.
-	Trace("%s", fmt.Sprint(v...))
+	l.logf(logLevelTrace, 0, nil, "%s", []interface{}{fmt.Sprint(v...)})
 }
 
 func (l Logger) Printf(format string, v ...interface{}) {
-	Trace(format, v...)
+	l.logf(logLevelTrace, 0, nil, format, v)
 }
 
 // Gorm structure used for Gorm SQL query logging
@@ -263,6 +329,9 @@ func (l Gorm) Print(args ...interface{}) {
 				args[5],
 			}
 			Query("Query=[%v], Values=%v Duration=[%v], Rows=[%v]", messages...)
+			if d, ok := args[2].(time.Duration); ok {
+				RecordQuery(fmt.Sprint(args[3]), d)
+			}
 		}
 	case "log":
 		if len(args) >= 3 {
@@ -277,32 +346,41 @@ func (l Gorm) Print(args ...interface{}) {
 
 // logger
 type logger struct {
-	file   *os.File
-	level  int
-	day    int
-	size   int64
-	purged time.Time
-	lock   sync.Mutex
+	file     File
+	filename string
+	level    int
+	day      int
+	size     int64
+	stamp    int64  // timestamp in the name of the current file
+	prefix   string // path prefix and level name of the current file
+	purged   time.Time
+	checked  time.Time // last check of the current file
+	lock     sync.Mutex
 }
 
 func (l *logger) log(t time.Time, data []byte) {
-	y, m, d := t.Date()
+	_, _, d := t.Date()
 
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
+	// loaded under the lock, so a file closed by closeFiles is not reopened
+	// with the previous configuration
+	conf := loadConf()
+
 	// Purge once in 24 hours
-	if l.purged.IsZero() || -time.Until(l.purged) > (24*time.Hour) {
-		gConf.purgeLock.Lock()
+	if l.purged.IsZero() || t.Sub(l.purged) > (24*time.Hour) {
+		gPurgeLock.Lock()
 		hasLocked := true
 
 		defer func() {
 			if hasLocked {
-				gConf.purgeLock.Unlock()
+				gPurgeLock.Unlock()
 			}
 		}()
 
-		filepath.Walk(gConf.logPath, func(path string, info os.FileInfo, e error) error {
+		fs := conf.filesystem()
+		fs.Walk(conf.logPath, func(path string, info os.FileInfo, e error) error {
 			if e != nil {
 				return e
 			}
@@ -315,25 +393,30 @@ func (l *logger) log(t time.Time, data []byte) {
 				return nil
 			}
 
-			if -time.Until(info.ModTime()) > (time.Hour * 24 * time.Duration(gConf.maxdays)) {
-				e = os.Remove(path)
+			if t.Sub(info.ModTime()) > (time.Hour * 24 * time.Duration(conf.maxdays)) {
+				e = fs.Remove(path)
 				if e != nil {
-					l.errlog(t, nil, e)
+					l.errlog(conf, t, nil, e)
 				}
 			}
 
 			return e
 		})
 
-		l.purged = time.Now()
-		gConf.purgeLock.Unlock()
+		l.purged = t
+		gPurgeLock.Unlock()
 		hasLocked = false
 	}
 
-	// Decide whether we can reuse current file: same day and within size limit.
+	prefix := conf.filePrefix(l.level)
+	if l.file != nil && l.prefix == prefix {
+		l.check(conf, t)
+	}
+
+	// Decide whether we can reuse current file: same day, same path and within size limit.
 	canReuse := false
-	if l.file != nil && l.day == d {
-		if gMaxFileSizeBytes <= 0 || (l.size+int64(len(data)) < gMaxFileSizeBytes) {
+	if l.file != nil && l.day == d && l.prefix == prefix {
+		if conf.maxFileSize <= 0 || (l.size+int64(len(data)) < conf.maxFileSize) {
 			canReuse = true
 		}
 	}
@@ -344,37 +427,122 @@ func (l *logger) log(t time.Time, data []byte) {
 		return
 	}
 
-	// Need to open a new file (new day, first open, or size exceeded).
-	// Use a nano timestamp suffix to generate unique filenames on rotation.
-	filename := fmt.Sprintf("%s%s_%d%02d%02d.%d.log", gConf.pathPrefix, gLogLevelNames[l.level], y, m, d, time.Now().UnixNano())
-	newfile, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		l.errlog(t, data, err)
+	// Need to open a new file (new day, first open, changed path or size exceeded).
+	if err := l.open(conf, t); err != nil {
+		l.errlog(conf, t, data, err)
 		return
 	}
 
+	n, _ := l.file.Write(data)
+	l.size += int64(n)
+}
+
+// open opens a new logfile for the time t and links the symlink to it, the
+// current file is closed. It must be called with l.lock held.
+func (l *logger) open(conf *config, t time.Time) error {
+	y, m, d := t.Date()
+
+	// Use a nano timestamp suffix to generate unique filenames on rotation,
+	// kept increasing in case the clock does not move.
+	stamp := t.UnixNano()
+	if stamp <= l.stamp {
+		stamp = l.stamp + 1
+	}
+	prefix := conf.filePrefix(l.level)
+	filename := fmt.Sprintf("%s_%d%02d%02d.%d.log", prefix, y, m, d, stamp)
+	newfile, err := conf.filesystem().OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
 	if l.file != nil {
 		_ = l.file.Close()
 	}
 	l.file = newfile
+	l.filename = filename
 	l.day = d
 	l.size = 0
+	l.stamp = stamp
+	l.prefix = prefix
+	l.checked = t
 
-	err = os.RemoveAll(gFullSymlinks[l.level])
+	symlink := conf.symlink(l.level)
+	err = conf.filesystem().RemoveAll(symlink)
 	if err != nil {
-		l.errlog(t, nil, err)
+		l.errlog(conf, t, nil, err)
 	}
-	_ = os.Symlink(path.Base(filename), gFullSymlinks[l.level])
+	_ = conf.filesystem().Symlink(path.Base(filename), symlink)
+	return nil
+}
 
-	n, _ := l.file.Write(data)
-	l.size += int64(n)
+// reopen closes the current file and opens the file with its name again,
+// which is created if it was moved away.
+func (l *logger) reopen() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	conf := loadConf()
+	if l.prefix != conf.filePrefix(l.level) {
+		// the next log opens a file under the new path
+		_ = l.file.Close()
+		l.file = nil
+		return nil
+	}
+
+	return l.reopenFile(conf)
+}
+
+// reopenFile opens the file with the name of the current file, which is
+// created if it was moved away, and closes the current file. It must be called
+// with l.lock held.
+func (l *logger) reopenFile(conf *config) error {
+	newfile, err := conf.filesystem().OpenFile(l.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_ = l.file.Close()
+	l.file = newfile
+	l.size = 0
+	if fi, err := newfile.Stat(); err == nil {
+		l.size = fi.Size()
+	}
+	return nil
+}
+
+// close closes the current file, the next log opens a new one.
+func (l *logger) close() {
+	l.lock.Lock()
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+	l.lock.Unlock()
+}
+
+// rotate opens a new logfile, if a file is open.
+func (l *logger) rotate() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	conf := loadConf()
+	return l.open(conf, conf.now())
 }
 
 // (l *logger).errlog() should only be used within (l *logger).log()
-func (l *logger) errlog(t time.Time, originLog []byte, err error) {
+func (l *logger) errlog(conf *config, t time.Time, originLog []byte, err error) {
 	buf := gBufPool.getBuffer()
 
-	genLogPrefix(buf, l.level, 2, t)
+	logLevel := l.level
+	if logLevel == logLevelAll {
+		logLevel = logLevelError
+	}
+	genLogPrefix(buf, conf, logLevel, 2, t)
 	buf.WriteString(err.Error())
 	buf.WriteByte('\n')
 	if l.file != nil {
@@ -400,24 +568,18 @@ func init() {
 	tmpProgname := strings.Split(gProgname, "\\") // for compatible with `go run` under Windows
 	gProgname = tmpProgname[len(tmpProgname)-1]
 
-	gConf.setFilenamePrefix(DefFilenamePrefix, DefSymlinkPrefix)
+	gConfValue.Store(defaultConfig())
 }
 
-func genLogPrefix(buf *buffer, logLevel, skip int, t time.Time) {
-	h, m, s := t.Clock()
-
+func genLogPrefix(buf *buffer, conf *config, logLevel, skip int, t time.Time) {
 	// time
-	buf.tmp[0] = logLevelChar[logLevel]
-	buf.twoDigits(1, h)
-	buf.tmp[3] = ':'
-	buf.twoDigits(4, m)
-	buf.tmp[6] = ':'
-	buf.twoDigits(7, s)
-	buf.Write(buf.tmp[:9])
+	buf.tmp[0] = conf.levels[logLevel].char
+	n := buf.timestamp(1, t, &conf.timeOpts)
+	buf.Write(buf.tmp[:n])
 
 	var pc uintptr
 	var ok bool
-	if gConf.logFilenameLineNum() {
+	if conf.logFilenameLineNum() {
 		var file string
 		var line int
 		pc, file, line, ok = runtime.Caller(skip)
@@ -429,7 +591,7 @@ func genLogPrefix(buf *buffer, logLevel, skip int, t time.Time) {
 			buf.Write(buf.tmp[:n+1])
 		}
 	}
-	if gConf.logFuncName() {
+	if conf.logFuncName() {
 		if !ok {
 			pc, _, _, ok = runtime.Caller(skip)
 		}
@@ -438,43 +600,61 @@ func genLogPrefix(buf *buffer, logLevel, skip int, t time.Time) {
 			buf.WriteString(runtime.FuncForPC(pc).Name())
 		}
 	}
-	if infoHostName != "" {
+	if conf.hostName != "" {
 		buf.WriteByte(' ')
-		buf.WriteString(infoHostName)
+		buf.WriteString(conf.hostName)
 	}
-	if infoUserName != "" {
+	if conf.userName != "" {
 		buf.WriteByte(' ')
-		buf.WriteString(infoUserName)
+		buf.WriteString(conf.userName)
 	}
 
 	buf.WriteString("] ")
 }
 
 func log(logLevel int, format string, args []interface{}) {
-	if !gConf.isEnabled() {
+	logDepth(logLevel, 1, nil, format, args)
+}
+
+// logDepth works like log, depth is the number of stack frames between the
+// function calling logDepth and the caller reported in the log. The fields, if
+// any, are written after the message.
+func logDepth(logLevel, depth int, fields Fields, format string, args []interface{}) {
+	conf := loadConf()
+	if !conf.isEnabled() {
 		fmt.Println("Logger disabled")
 		return
 	}
 
+	fields = conf.withBacktrace(depth+1, fields)
 	buf := gBufPool.getBuffer()
 
-	t := time.Now()
-	genLogPrefix(buf, logLevel, 3, t)
-	fmt.Fprintf(buf, format, args...)
-	buf.WriteByte('\n')
+	t := conf.now()
+	writeEntry(buf, conf, logLevel, 3+depth, t, fields, format, args)
 	output := buf.Bytes()
-	if gConf.logThrough() {
-		for i := logLevel; i != logLevelTrace; i-- {
-			gLoggers[i].log(t, output)
+	conf.loggers[logLevel].log(t, output)
+	if conf.logThrough() {
+		for _, i := range conf.through[logLevel] {
+			conf.loggers[i].log(t, output)
 		}
-		if gConf.logTrace() {
-			gLoggers[logLevelTrace].log(t, output)
+		// a trace log may be turned on for its caller only
+		if conf.logTrace() && logLevel != logLevelTrace {
+			conf.loggers[logLevelTrace].log(t, output)
 		}
-	} else {
-		gLoggers[logLevel].log(t, output)
 	}
-	if gConf.logToConsole() {
-		fmt.Print(string(output))
+	if conf.logAll() {
+		conf.all.log(t, output)
+	}
+	sinks := loadSinks()
+	if conf.logToConsole() {
+		if conf.format == FormatText {
+			sinks = append([]Sink{conf.console}, sinks...)
+		} else {
+			conf.console.writeLine(conf, Level(logLevel), output)
+		}
+	}
+	if len(sinks) > 0 {
+		logToSinks(sinks, logLevel, 2+depth, t, fields, format, args)
 	}
 
 	gBufPool.returnBuffer(buf)
@@ -482,101 +662,6 @@ func log(logLevel int, format string, args []interface{}) {
 
 var gProgname = path.Base(os.Args[0])
 
-var gLogLevelNames = [logLevelMax]string{
-	"trace", "info", "warn", "error", "update", "panic", "abort", "query", "debug",
-}
-
-var gSymlinks [logLevelMax]string
-var isSymlink map[string]bool
-var gFullSymlinks [logLevelMax]string
 var gBufPool bufferPool
-var gLoggers [logLevelMax]logger
-
-// gMaxFileSizeBytes controls rotation by size when > 0. Default 0 (disabled).
-
-// EnrichHTTPMeta populates and returns a metadata map with useful diagnostic
-// information for HTTP error logging. It mirrors the enrichment previously
-// performed in controller.jsonErrorResponseWithMeta so callers can reuse the
-// same behavior from the central logger package.
-//
-// Provided fields:
-//   - origin, originFile, originLine (caller info)
-//   - method, path, query (from *http.Request)
-//   - selected headers: X-Request-Id, UserName, Application-Version, User-Agent
-//   - timestamp (human-readable), hostname
-//   - stack (short stack trace) when status >= 500
-func EnrichHTTPMeta(status int, req *http.Request, meta map[string]interface{}, callerSkip int) map[string]interface{} {
-	if meta == nil {
-		meta = map[string]interface{}{}
-	}
-
-	// origin information. callerSkip allows the caller to control how many
-	// stack frames to skip so origin points to the real caller (for example,
-	// when called from a wrapper in another package).
-	if _, ok := meta["origin"]; !ok {
-		if pc, file, line, okc := runtime.Caller(callerSkip); okc {
-			if fn := runtime.FuncForPC(pc); fn != nil {
-				meta["origin"] = path.Base(fn.Name())
-			}
-			if _, okf := meta["originFile"]; !okf {
-				meta["originFile"] = file
-			}
-			if _, okl := meta["originLine"]; !okl {
-				meta["originLine"] = line
-			}
-		}
-	}
-
-	// request information
-	if req != nil {
-		if _, ok := meta["method"]; !ok {
-			meta["method"] = req.Method
-		}
-		if _, ok := meta["path"]; !ok {
-			meta["path"] = req.URL.Path
-		}
-		if _, ok := meta["query"]; !ok {
-			meta["query"] = req.URL.RawQuery
-		}
-		headersToCopy := []string{"X-Request-Id", "UserName", "Application-Version", "User-Agent"}
-		for _, h := range headersToCopy {
-			if _, ok := meta[h]; !ok {
-				if v := req.Header.Get(h); v != "" {
-					meta[h] = v
-				}
-			}
-		}
-	}
-
-	// timestamp and hostname
-	if _, ok := meta["timestamp"]; !ok {
-		meta["timestamp"] = time.Now().Format("2006-01-02 15:04:05 MST")
-	}
-	if _, ok := meta["hostname"]; !ok {
-		if h, err := os.Hostname(); err == nil {
-			meta["hostname"] = h
-		}
-	}
-
-	// stack for server errors
-	if status >= http.StatusInternalServerError {
-		if _, ok := meta["stack"]; !ok {
-			pcs := make([]uintptr, 10)
-			n := runtime.Callers(2, pcs)
-			frames := runtime.CallersFrames(pcs[:n])
-			var b strings.Builder
-			for i := 0; i < 6; i++ {
-				f, more := frames.Next()
-				fmt.Fprintf(&b, "%s:%d %s\n", f.File, f.Line, path.Base(f.Function))
-				if !more {
-					break
-				}
-			}
-			meta["stack"] = b.String()
-		}
-	}
-
-	return meta
-}
 
 // (test helpers moved to test_helpers_test.go)
@@ -0,0 +1,78 @@
+// Package logtest routes the logs of the code under test to the testing.TB of
+// the test:
+//
+//	func TestCharge(t *testing.T) {
+//		ctx := log.ContextWithLogger(context.Background(), logtest.NewTestLogger(t))
+//		charge(ctx)
+//	}
+//
+// It lives in its own package so programs importing the go-log package do
+// not link the testing package.
+package logtest
+
+import (
+	"sync"
+	"testing"
+
+	log "github.com/dainiauskas/go-log"
+)
+
+// TestLoggerOptions configures the Logger returned by NewTestLoggerWithOptions.
+type TestLoggerOptions struct {
+	// FailOnError marks the test as failed when an entry with a level at
+	// least as severe as error is logged, compared by Rank, e.g. error,
+	// panic, abort or a registered level of a higher rank.
+	FailOnError bool
+}
+
+// NewTestLogger returns a Logger writing to t.Log, so the logs are shown
+// only for failing tests (or with -v) and are attributed to the test or
+// subtest t. Pass it to the code under test directly or with
+// log.ContextWithLogger. Logs written after the test completed are dropped.
+func NewTestLogger(t testing.TB) log.Logger {
+	return NewTestLoggerWithOptions(t, TestLoggerOptions{})
+}
+
+// NewTestLoggerWithOptions works like NewTestLogger with the given options.
+func NewTestLoggerWithOptions(t testing.TB, opts TestLoggerOptions) log.Logger {
+	s := &testSink{t: t, opts: opts}
+	t.Cleanup(s.done)
+	return log.NewSinkLogger(s)
+}
+
+type testSink struct {
+	t        testing.TB
+	opts     TestLoggerOptions
+	lock     sync.RWMutex
+	finished bool
+}
+
+func (s *testSink) done() {
+	s.lock.Lock()
+	s.finished = true
+	s.lock.Unlock()
+}
+
+func (s *testSink) Log(e log.Entry) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.finished {
+		return
+	}
+
+	line := string(e.Level.Char()) + e.Time.Format("15:04:05.000")
+	if c := e.Caller.String(); c != "" {
+		line += " " + c
+	}
+	line += "] " + e.Message
+	if len(e.Fields) > 0 {
+		line += " " + e.Fields.String()
+	}
+
+	switch {
+	case s.opts.FailOnError && e.Level.Rank() >= log.LevelError.Rank():
+		s.t.Error(line)
+	default:
+		s.t.Log(line)
+	}
+}
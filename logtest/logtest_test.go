@@ -0,0 +1,90 @@
+package logtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/dainiauskas/go-log"
+)
+
+// recordingTB captures the output of the test logger.
+type recordingTB struct {
+	testing.TB
+	logs   []string
+	errors []string
+}
+
+func (r *recordingTB) Log(args ...interface{})   { r.logs = append(r.logs, args[0].(string)) }
+func (r *recordingTB) Error(args ...interface{}) { r.errors = append(r.errors, args[0].(string)) }
+
+func TestNewTestLogger_RoutesToTest(t *testing.T) {
+	dir := t.TempDir()
+	if err := log.Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	log.SetFilenamePrefix("tl", "tl")
+
+	rec := &recordingTB{TB: t}
+	l := NewTestLogger(rec)
+	l.Info("hello %d", 1)
+	log.ErrorContext(log.ContextWithLogger(log.ContextWithRequestID(context.Background(), "r-2"), l), "boom")
+	log.Info("global")
+
+	if len(rec.logs) != 2 || len(rec.errors) != 0 {
+		t.Fatalf("unexpected test output logs=%q errors=%q", rec.logs, rec.errors)
+	}
+	if !strings.HasPrefix(rec.logs[0], "I") || !strings.Contains(rec.logs[0], "logtest_test.go:") || !strings.HasSuffix(rec.logs[0], "] hello 1") {
+		t.Fatalf("unexpected line %q", rec.logs[0])
+	}
+	if !strings.HasSuffix(rec.logs[1], "] boom request_id=r-2") {
+		t.Fatalf("unexpected line %q", rec.logs[1])
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "tl.info"))
+	if err != nil {
+		t.Fatalf("failed to read the info logfile: %v", err)
+	}
+	if out := string(data); strings.Contains(out, "hello") || strings.Contains(out, "boom") || !strings.Contains(out, "global") {
+		t.Fatalf("expected only global logs in the logfiles, got:\n%s", out)
+	}
+}
+
+func TestNewTestLoggerWithOptions_FailOnError(t *testing.T) {
+	security, err := log.RegisterLevel("security", 'S', log.LevelError.Rank()+5)
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+	access, err := log.RegisterLevel("access", 'X', log.LevelInfo.Rank()+5)
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+
+	rec := &recordingTB{TB: t}
+	l := NewTestLoggerWithOptions(rec, TestLoggerOptions{FailOnError: true})
+	l.Warn("fine")
+	l.Log(access, "GET /")
+	l.Error("broken %s", "pipe")
+	l.Log(security, "intrusion")
+
+	if len(rec.logs) != 2 || len(rec.errors) != 2 || !strings.HasSuffix(rec.errors[0], "] broken pipe") ||
+		!strings.HasPrefix(rec.errors[1], "S") {
+		t.Fatalf("unexpected test output logs=%q errors=%q", rec.logs, rec.errors)
+	}
+}
+
+func TestNewTestLogger_DropsLogsAfterTest(t *testing.T) {
+	var l log.Logger
+	var rec *recordingTB
+	t.Run("sub", func(t *testing.T) {
+		rec = &recordingTB{TB: t}
+		l = NewTestLogger(rec)
+		l.Info("during")
+	})
+	l.Info("after")
+
+	if len(rec.logs) != 1 {
+		t.Fatalf("expected logs after the test to be dropped, got %q", rec.logs)
+	}
+}
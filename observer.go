@@ -0,0 +1,95 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Observer is a sink keeping the entries in memory, for tests:
+//
+//	obs := log.NewObserver()
+//	defer log.AddSink(obs)()
+//	...
+//	if obs.FilterLevel(log.LevelError).Len() != 0 {
+//		t.Fatalf("unexpected errors: %v", obs.FilterLevel(log.LevelError).All())
+//	}
+//
+// The Filter methods return a new Observer holding a copy of the matching
+// entries, which is not attached to the logger.
+type Observer struct {
+	lock    sync.Mutex
+	entries []Entry
+}
+
+// NewObserver returns an empty Observer, add it with AddSink to observe the
+// entries.
+func NewObserver() *Observer {
+	return &Observer{}
+}
+
+// Log implements Sink.
+func (o *Observer) Log(e Entry) {
+	o.lock.Lock()
+	o.entries = append(o.entries, e)
+	o.lock.Unlock()
+}
+
+// Len returns the number of observed entries.
+func (o *Observer) Len() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return len(o.entries)
+}
+
+// All returns a copy of the observed entries.
+func (o *Observer) All() []Entry {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return append([]Entry(nil), o.entries...)
+}
+
+// TakeAll returns the observed entries and forgets them.
+func (o *Observer) TakeAll() []Entry {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	entries := o.entries
+	o.entries = nil
+	return entries
+}
+
+// Filter returns the entries for which keep returns true.
+func (o *Observer) Filter(keep func(e Entry) bool) *Observer {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	filtered := &Observer{}
+	for _, e := range o.entries {
+		if keep(e) {
+			filtered.entries = append(filtered.entries, e)
+		}
+	}
+	return filtered
+}
+
+// FilterLevel returns the entries with the given level.
+func (o *Observer) FilterLevel(level Level) *Observer {
+	return o.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// FilterMessage returns the entries with the given message.
+func (o *Observer) FilterMessage(msg string) *Observer {
+	return o.Filter(func(e Entry) bool { return e.Message == msg })
+}
+
+// FilterMessageSnippet returns the entries whose message contains snippet.
+func (o *Observer) FilterMessageSnippet(snippet string) *Observer {
+	return o.Filter(func(e Entry) bool { return strings.Contains(e.Message, snippet) })
+}
+
+// FilterField returns the entries having the field key with the given value.
+func (o *Observer) FilterField(key string, value interface{}) *Observer {
+	return o.Filter(func(e Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(v, value)
+	})
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestObserver_CapturesEntries(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	obs := NewObserver()
+	remove := AddSink(obs)
+
+	Info("hello %s", "world")
+	WarnContext(ContextWithFields(context.Background(), Fields{"order": 7, "tags": []string{"a"}}), "low stock")
+	Trace("muted")
+	remove()
+	Error("not observed")
+
+	if obs.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", obs.Len(), obs.All())
+	}
+
+	info := obs.FilterLevel(LevelInfo).All()
+	if len(info) != 1 || info[0].Message != "hello world" {
+		t.Fatalf("unexpected info entries %+v", info)
+	}
+	if info[0].Caller.String() == "" || info[0].Caller.Function != "github.com/dainiauskas/go-log.TestObserver_CapturesEntries" {
+		t.Fatalf("unexpected caller %+v", info[0].Caller)
+	}
+	if obs.FilterMessage("low stock").FilterField("order", 7).FilterField("tags", []string{"a"}).Len() != 1 {
+		t.Fatalf("expected warn entry with fields, got %+v", obs.All())
+	}
+	if obs.FilterMessageSnippet("world").Len() != 1 {
+		t.Fatalf("expected entry containing world")
+	}
+
+	if taken := obs.TakeAll(); len(taken) != 2 || obs.Len() != 0 {
+		t.Fatalf("expected TakeAll to return and forget entries, got %d left %d", len(taken), obs.Len())
+	}
+}
@@ -0,0 +1,375 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// querySamples is the number of durations kept per fingerprint to compute
+// the percentiles.
+const querySamples = 1024
+
+// querySummaryTop is the number of fingerprints written in a summary.
+const querySummaryTop = 20
+
+// QueryStat holds the aggregated durations of the statements sharing a
+// fingerprint.
+type QueryStat struct {
+	Fingerprint string
+	Example     string // first statement seen
+	Count       int64
+	Total       time.Duration
+	P50         time.Duration
+	P95         time.Duration
+	Max         time.Duration
+}
+
+type queryAgg struct {
+	example string
+	count   int64
+	total   time.Duration
+	max     time.Duration
+	samples []time.Duration
+}
+
+type queryStats struct {
+	enabled int32 // atomic
+	lock    sync.Mutex
+	aggs    map[string]*queryAgg
+
+	// summaryLock is held while the summaries are stopped and started, not
+	// lock, as a summary being written takes lock
+	summaryLock sync.Mutex
+	stop        chan struct{} // closed to stop the summaries
+	done        chan struct{} // closed when the summaries stopped
+}
+
+var gQueryStats = queryStats{aggs: map[string]*queryAgg{}}
+
+// EnableQueryStats starts aggregating the statements passed to RecordQuery,
+// which the Gorm adapters and the sqllog driver wrapper do for every query.
+// When interval > 0, a summary of the slowest fingerprints is logged with
+// query level every interval and the statistics are reset. The interval is
+// counted on the clock set with SetClock if it is a TimerClock.
+func EnableQueryStats(interval time.Duration) {
+	s := &gQueryStats
+	s.summaryLock.Lock()
+	defer s.summaryLock.Unlock()
+
+	s.stopSummaries()
+	if interval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.summarize(interval, loadConf().newTicker(interval), s.stop, s.done)
+	}
+	atomic.StoreInt32(&s.enabled, 1)
+}
+
+// DisableQueryStats stops aggregating statements, the collected statistics
+// are kept until ResetQueryStats.
+func DisableQueryStats() {
+	s := &gQueryStats
+	atomic.StoreInt32(&s.enabled, 0)
+	s.summaryLock.Lock()
+	s.stopSummaries()
+	s.summaryLock.Unlock()
+}
+
+// QueryStatsEnabled reports whether the statements passed to RecordQuery are
+// aggregated, so callers can skip rendering statements nobody records.
+func QueryStatsEnabled() bool {
+	return atomic.LoadInt32(&gQueryStats.enabled) != 0
+}
+
+// stopSummaries stops the periodic summaries and waits until a summary being
+// written is done. It must be called with s.summaryLock held.
+func (s *queryStats) stopSummaries() {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop, s.done = nil, nil
+	}
+}
+
+// ResetQueryStats drops the collected statistics.
+func ResetQueryStats() {
+	s := &gQueryStats
+	s.lock.Lock()
+	s.aggs = map[string]*queryAgg{}
+	s.lock.Unlock()
+}
+
+// RecordQuery adds a statement which took d to the statistics of its
+// fingerprint. It does nothing unless EnableQueryStats was called.
+func RecordQuery(sql string, d time.Duration) {
+	s := &gQueryStats
+	if atomic.LoadInt32(&s.enabled) == 0 || sql == "" {
+		return
+	}
+	fp := Fingerprint(sql)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	agg := s.aggs[fp]
+	if agg == nil {
+		agg = &queryAgg{example: sql}
+		s.aggs[fp] = agg
+	}
+	agg.count++
+	agg.total += d
+	if d > agg.max {
+		agg.max = d
+	}
+	// reservoir sampling keeps a uniform sample of the durations
+	if len(agg.samples) < querySamples {
+		agg.samples = append(agg.samples, d)
+	} else if i := rand.Int63n(agg.count); i < querySamples {
+		agg.samples[i] = d
+	}
+}
+
+// QueryStats returns the statistics per fingerprint, slowest total first.
+func QueryStats() []QueryStat {
+	s := &gQueryStats
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.snapshot()
+}
+
+func (s *queryStats) snapshot() []QueryStat {
+	stats := make([]QueryStat, 0, len(s.aggs))
+	for fp, agg := range s.aggs {
+		samples := append([]time.Duration(nil), agg.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		stats = append(stats, QueryStat{
+			Fingerprint: fp,
+			Example:     agg.example,
+			Count:       agg.count,
+			Total:       agg.total,
+			P50:         percentile(samples, 50),
+			P95:         percentile(samples, 95),
+			Max:         agg.max,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func (s *queryStats) summarize(interval time.Duration, ticker *ticker, stop, done chan struct{}) {
+	defer close(done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.lock.Lock()
+			stats := s.snapshot()
+			s.aggs = map[string]*queryAgg{}
+			s.lock.Unlock()
+
+			if len(stats) > 0 {
+				logDepth(logLevelQuery, 0, nil, "%s", []interface{}{formatQueryStats(stats, interval)})
+			}
+		}
+	}
+}
+
+// formatQueryStats formats the slowest fingerprints as a table.
+func formatQueryStats(stats []QueryStat, interval time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query summary for the last %v: %d fingerprints", interval, len(stats))
+	if len(stats) > querySummaryTop {
+		stats = stats[:querySummaryTop]
+	}
+	for _, st := range stats {
+		fmt.Fprintf(&b, "\n  count=%d total=%v p50=%v p95=%v max=%v query=[%s]",
+			st.Count, st.Total, st.P50, st.P95, st.Max, st.Fingerprint)
+	}
+	return b.String()
+}
+
+// QueryStatsHandler returns an http.Handler reporting QueryStats as JSON, or
+// as plain text with ?format=text. The report is limited to the first n
+// fingerprints with ?limit=n.
+func QueryStatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stats := QueryStats()
+		var limit int
+		if _, err := fmt.Sscan(req.URL.Query().Get("limit"), &limit); err == nil && limit >= 0 && limit < len(stats) {
+			stats = stats[:limit]
+		}
+
+		if req.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			for _, st := range stats {
+				fmt.Fprintf(w, "count=%d total=%v p50=%v p95=%v max=%v query=[%s]\n",
+					st.Count, st.Total, st.P50, st.P95, st.Max, st.Fingerprint)
+			}
+			return
+		}
+
+		type jsonStat struct {
+			Fingerprint string  `json:"fingerprint"`
+			Example     string  `json:"example"`
+			Count       int64   `json:"count"`
+			TotalMs     float64 `json:"total_ms"`
+			P50Ms       float64 `json:"p50_ms"`
+			P95Ms       float64 `json:"p95_ms"`
+			MaxMs       float64 `json:"max_ms"`
+		}
+		ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+		out := make([]jsonStat, len(stats))
+		for i, st := range stats {
+			out[i] = jsonStat{st.Fingerprint, st.Example, st.Count, ms(st.Total), ms(st.P50), ms(st.P95), ms(st.Max)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+}
+
+// Fingerprint normalises a SQL statement: literals and placeholders are
+// replaced with ?, IN-lists and multi-row VALUES are collapsed, whitespace is
+// squeezed and everything is lower-cased. Statements differing only in their
+// literals share a fingerprint.
+func Fingerprint(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	space := false
+	rs := []rune(sql)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case r == '-' && i+1 < len(rs) && rs[i+1] == '-':
+			// line comment
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			// block comment
+			i += 2
+			for i+1 < len(rs) && !(rs[i] == '*' && rs[i+1] == '/') {
+				i++
+			}
+			i++
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case r == '\'':
+			// string literal, quotes are escaped by doubling or a backslash
+			for i++; i < len(rs); i++ {
+				if rs[i] == '\\' {
+					i++
+				} else if rs[i] == r {
+					if i+1 < len(rs) && rs[i+1] == r {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+		case r == '$' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			// positional placeholder
+			for i+1 < len(rs) && unicode.IsDigit(rs[i+1]) {
+				i++
+			}
+			b.WriteByte('?')
+		case unicode.IsDigit(r) && !identifierEnd(&b):
+			// number, including hex, decimals and exponents
+			for i+1 < len(rs) && (unicode.IsDigit(rs[i+1]) || unicode.IsLetter(rs[i+1]) || rs[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return collapseLists(b.String())
+}
+
+// identifierEnd reports whether b ends within an identifier, so a following
+// digit is part of it.
+func identifierEnd(b *strings.Builder) bool {
+	s := b.String()
+	if s == "" {
+		return false
+	}
+	c := rune(s[len(s)-1])
+	return unicode.IsLetter(c) || c == '_' || c == '`' || c == '.'
+}
+
+// collapseLists collapses lists of placeholders like `(?, ?, ?)` into `(?+)`
+// and repeated `(?+), (?+)` tuples of multi-row VALUES into one.
+func collapseLists(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '(' {
+			b.WriteByte(s[i])
+			continue
+		}
+		// a list made only of ?, commas and spaces
+		j := i + 1
+		n := 0
+		for j < len(s) && (s[j] == '?' || s[j] == ',' || s[j] == ' ') {
+			if s[j] == '?' {
+				n++
+			}
+			j++
+		}
+		if j < len(s) && s[j] == ')' && n > 0 {
+			const tuple = "(?+)"
+			if strings.HasSuffix(b.String(), tuple+", ") || strings.HasSuffix(b.String(), tuple+",") {
+				// repeated tuple of multi-row VALUES
+				trimmed := strings.TrimRight(strings.TrimSuffix(strings.TrimRight(b.String(), " "), ","), " ")
+				b.Reset()
+				b.WriteString(trimmed)
+			} else {
+				b.WriteString(tuple)
+			}
+			i = j
+			continue
+		}
+		b.WriteByte('(')
+	}
+	return b.String()
+}
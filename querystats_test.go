@@ -0,0 +1,144 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM users WHERE id = 42":                         "select * from users where id = ?",
+		"select *  from users\n\twhere id=7":                        "select * from users where id=?",
+		"SELECT name FROM t1 WHERE name = 'O''Brien' AND x = 1.5e3": "select name from t1 where name = ? and x = ?",
+		"SELECT * FROM users WHERE id IN (1, 2, 3)":                 "select * from users where id in (?+)",
+		"SELECT * FROM users WHERE id IN (?, ?)":                    "select * from users where id in (?+)",
+		"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z')":  "insert into t (a, b) values (?+)",
+		"UPDATE t SET a = $1 WHERE b = $2 -- comment":               "update t set a = ? where b = ?",
+		`SELECT "id" FROM "users" /* hint */ WHERE x = 0x1F`:        `select "id" from "users" where x = ?`,
+	}
+	for in, want := range cases {
+		if got := Fingerprint(in); got != want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestQueryStats_Aggregates(t *testing.T) {
+	ResetQueryStats()
+	RecordQuery("SELECT 1", time.Millisecond)
+	if len(QueryStats()) != 0 {
+		t.Fatalf("expected nothing recorded while disabled")
+	}
+
+	EnableQueryStats(0)
+	defer DisableQueryStats()
+	defer ResetQueryStats()
+
+	for i := 1; i <= 100; i++ {
+		RecordQuery("SELECT * FROM users WHERE id = "+strings.Repeat("9", i%3+1), time.Duration(i)*time.Millisecond)
+	}
+	RecordQuery("DELETE FROM sessions", time.Second)
+
+	stats := QueryStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 fingerprints, got %d: %+v", len(stats), stats)
+	}
+	users := stats[0]
+	if users.Fingerprint != "select * from users where id = ?" || users.Count != 100 {
+		t.Fatalf("unexpected first stat %+v", users)
+	}
+	if users.Total != 5050*time.Millisecond || users.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected total/max %+v", users)
+	}
+	if users.P50 != 50*time.Millisecond || users.P95 != 95*time.Millisecond {
+		t.Fatalf("unexpected percentiles p50=%v p95=%v", users.P50, users.P95)
+	}
+}
+
+func TestQueryStatsHandler(t *testing.T) {
+	ResetQueryStats()
+	EnableQueryStats(0)
+	defer DisableQueryStats()
+	defer ResetQueryStats()
+
+	RecordQuery("SELECT * FROM a WHERE x = 1", 2*time.Millisecond)
+	RecordQuery("SELECT * FROM b WHERE x = 1", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	QueryStatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?limit=1", nil))
+	var report []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	if len(report) != 1 || report[0]["fingerprint"] != "select * from a where x = ?" || report[0]["total_ms"] != float64(2) {
+		t.Fatalf("unexpected report %v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	QueryStatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=text", nil))
+	if strings.Count(rec.Body.String(), "\n") != 2 || !strings.Contains(rec.Body.String(), "query=[select * from b where x = ?]") {
+		t.Fatalf("unexpected text report:\n%s", rec.Body.String())
+	}
+}
+
+func TestQueryStats_PeriodicSummary(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("qs", "qs")
+	clock := &fakeClock{t: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	entries := make(entrySink, 16)
+	defer AddSink(entries)()
+
+	ResetQueryStats()
+	EnableQueryStats(time.Minute)
+	var g Gorm
+	g.Print("sql", "SELECT * FROM t WHERE id = 1", 3*time.Millisecond, "SELECT * FROM t WHERE id = 1", []interface{}{}, int64(1))
+
+	clock.Add(time.Minute)
+	waitEntry(t, entries, "Query summary")
+	DisableQueryStats()
+
+	if out := readLogFile(t, dir, "qs.query"); !strings.Contains(out, "count=1 total=3ms") || !strings.Contains(out, "query=[select * from t where id = ?]") {
+		t.Fatalf("unexpected summary:\n%s", out)
+	}
+}
+
+func TestEnableQueryStats_Concurrent(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+	clock := &fakeClock{t: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	defer ResetQueryStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				EnableQueryStats(time.Minute)
+			}
+		}()
+	}
+	wg.Wait()
+	DisableQueryStats()
+
+	// every summary ticker started has been stopped
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	for _, timer := range clock.timers {
+		if !timer.done {
+			t.Fatal("expected no summaries left running")
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// RecoveryOptions configures the middleware returned by RecoveryWithOptions.
+type RecoveryOptions struct {
+	// Level is the level the recovered panic is logged with. The zero value,
+	// LevelTrace, is replaced by LevelError, as trace logs are muted by
+	// default.
+	Level Level
+	// StatusCode is the status of the response sent after a panic.
+	StatusCode int
+	// Body is the body of the response sent after a panic. If empty, the
+	// status text of StatusCode is used.
+	Body string
+	// Response, if set, writes the response after a panic instead of
+	// StatusCode and Body. v is the value returned by recover().
+	Response func(w http.ResponseWriter, req *http.Request, v interface{})
+}
+
+// DefaultRecoveryOptions returns the options used by Recovery: panics are
+// logged with error level and a plain 500 Internal Server Error is returned.
+func DefaultRecoveryOptions() RecoveryOptions {
+	return RecoveryOptions{
+		Level:      LevelError,
+		StatusCode: http.StatusInternalServerError,
+	}
+}
+
+// Recovery returns a middleware which recovers panics of the next handler,
+// logs them with the full stack and the request metadata from EnrichHTTPMeta
+// and responds with 500 Internal Server Error.
+func Recovery(next http.Handler) http.Handler {
+	return RecoveryWithOptions(next, DefaultRecoveryOptions())
+}
+
+// RecoveryWithOptions works like Recovery, but the level of the log and the
+// response are taken from opts.
+//
+// http.ErrAbortHandler is not recovered, so the server can abort the response
+// as usual.
+func RecoveryWithOptions(next http.Handler, opts RecoveryOptions) http.Handler {
+	if opts.Level == LevelTrace {
+		opts.Level = LevelError
+	}
+	if opts.StatusCode == 0 {
+		opts.StatusCode = http.StatusInternalServerError
+	}
+	return &recoveryHandler{next: next, opts: opts}
+}
+
+type recoveryHandler struct {
+	next http.Handler
+	opts RecoveryOptions
+}
+
+func (h *recoveryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rw := &recoveryWriter{ResponseWriter: w}
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			h.handlePanic(rw, req, v)
+		}
+	}()
+	h.next.ServeHTTP(rw, req)
+}
+
+// handlePanic must be called from the deferred function which recovered v,
+// so the panicking frames are still on the stack.
+func (h *recoveryHandler) handlePanic(w *recoveryWriter, req *http.Request, v interface{}) {
+	meta := map[string]interface{}{
+		"panic": fmt.Sprint(v),
+		"stack": string(debug.Stack()),
+	}
+	if f, ok := panicOrigin(); ok {
+		meta["origin"] = path.Base(f.Function)
+		meta["originFile"] = f.File
+		meta["originLine"] = f.Line
+	}
+	meta = EnrichHTTPMeta(http.StatusInternalServerError, req, meta, 1)
+
+	if h.opts.Level.valid() && loadConf().levelEnabled(int(h.opts.Level)) {
+		logDepth(int(h.opts.Level), 0, meta, "Recovered panic: %v", []interface{}{v})
+	}
+
+	if w.wroteHeader {
+		// the response has already started, nothing sensible can be sent
+		return
+	}
+	if h.opts.Response != nil {
+		h.opts.Response(w, req, v)
+		return
+	}
+	body := h.opts.Body
+	if body == "" {
+		body = http.StatusText(h.opts.StatusCode)
+	}
+	http.Error(w, body, h.opts.StatusCode)
+}
+
+// panicOrigin returns the frame which raised the panic being recovered.
+func panicOrigin() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	panicking := false
+	for {
+		f, more := frames.Next()
+		if panicking && !strings.HasPrefix(f.Function, "runtime.") {
+			return f, true
+		}
+		if f.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// recoveryWriter remembers whether the response has been started.
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoveryWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the wrapped writer does.
+func (w *recoveryWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the wrapped writer does, after which no
+// response can be sent.
+func (w *recoveryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+// Push implements http.Pusher if the wrapped writer does.
+func (w *recoveryWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panickingHandler(w http.ResponseWriter, req *http.Request) {
+	var m map[string]int
+	m["boom"] = 1
+}
+
+func TestRecovery_LogsPanicAndResponds500(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("rec", "rec")
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?id=7", nil)
+	req.Header.Set("X-Request-Id", "req-42")
+	rec := httptest.NewRecorder()
+	Recovery(http.HandlerFunc(panickingHandler)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+
+	out := readLogFile(t, dir, "rec.error")
+	for _, want := range []string{"Recovered panic", "assignment to entry in nil map", "method=POST", "path=/orders", "X-Request-Id=req-42", "origin=go-log.panickingHandler", "recovery_test.go"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in error log, got:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, "runtime/debug.Stack") && !strings.Contains(out, "goroutine") {
+		t.Fatalf("expected full stack in error log, got:\n%s", out)
+	}
+}
+
+func TestRecoveryWithOptions_ZeroOptions(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("rec", "rec")
+
+	rec := httptest.NewRecorder()
+	RecoveryWithOptions(http.HandlerFunc(panickingHandler), RecoveryOptions{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	if out := readLogFile(t, dir, "rec.error"); !strings.Contains(out, "Recovered panic") {
+		t.Fatalf("expected panic to be logged with error level, got:\n%s", out)
+	}
+}
+
+func TestRecoveryWithOptions_CustomLevelAndResponse(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("rec", "rec")
+	SetLogThrough(false)
+
+	opts := DefaultRecoveryOptions()
+	opts.Level = LevelPanic
+	opts.StatusCode = http.StatusServiceUnavailable
+	opts.Body = "try again later"
+
+	rec := httptest.NewRecorder()
+	RecoveryWithOptions(http.HandlerFunc(panickingHandler), opts).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "try again later") {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+	if out := readLogFile(t, dir, "rec.panic"); !strings.Contains(out, "Recovered panic") {
+		t.Fatalf("expected panic to be logged with panic level, got:\n%s", out)
+	}
+}
+
+func TestRecovery_ReraisesErrAbortHandler(t *testing.T) {
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to be re-raised, got %v", r)
+		}
+	}()
+	h := Recovery(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	pushed   string
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func (r *hijackRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = target
+	return nil
+}
+
+func TestRecovery_ForwardsHijackAndPush(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	Recovery(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := w.(http.Pusher).Push("/app.js", nil); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatalf("Hijack failed: %v", err)
+		}
+		if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != rec {
+			t.Fatalf("expected the wrapped writer unwrapped")
+		}
+		panic("after hijacking")
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rec.hijacked || rec.pushed != "/app.js" {
+		t.Fatalf("expected Hijack and Push forwarded, got %v %q", rec.hijacked, rec.pushed)
+	}
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected no response written on a hijacked connection, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	Recovery(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != http.ErrNotSupported {
+			t.Fatalf("expected Hijack not supported, got %v", err)
+		}
+		if err := w.(http.Pusher).Push("/app.js", nil); err != http.ErrNotSupported {
+			t.Fatalf("expected Push not supported, got %v", err)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// DefRequestIDHeader is the default header the request ID is read from and
+// written to.
+const DefRequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen is the longest request ID taken from a request.
+const maxRequestIDLen = 128
+
+// RequestIDOptions configures the middleware returned by RequestIDWithOptions.
+type RequestIDOptions struct {
+	// Header is the header holding the request ID, DefRequestIDHeader if empty.
+	Header string
+	// Traceparent enables taking the trace ID of a W3C `traceparent` header
+	// when Header is absent.
+	Traceparent bool
+	// Generate returns a new request ID when the request has none. If nil,
+	// NewRequestID is used.
+	Generate func() string
+}
+
+// RequestID returns a middleware which reads the request ID from the
+// X-Request-Id header or the trace ID of a W3C traceparent header, generating
+// one when both are absent, sets it on the response and stores it in the
+// request context. The context-aware log functions and EnrichHTTPMeta pick it
+// up from there. A request ID longer than 128 bytes or with characters other
+// than ASCII letters, digits and -_.:+/=@ is replaced by a generated one, so
+// clients cannot forge log lines.
+func RequestID(next http.Handler) http.Handler {
+	return RequestIDWithOptions(next, RequestIDOptions{Traceparent: true})
+}
+
+// RequestIDWithOptions works like RequestID, the header and the generator are
+// taken from opts.
+func RequestIDWithOptions(next http.Handler, opts RequestIDOptions) http.Handler {
+	if opts.Header == "" {
+		opts.Header = DefRequestIDHeader
+	}
+	if opts.Generate == nil {
+		opts.Generate = NewRequestID
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(opts.Header)
+		if !validRequestID(id) {
+			id = ""
+		}
+		if id == "" && opts.Traceparent {
+			id = traceIDFromTraceparent(req.Header.Get("traceparent"))
+		}
+		if id == "" {
+			id = opts.Generate()
+		}
+		w.Header().Set(opts.Header, id)
+		next.ServeHTTP(w, req.WithContext(ContextWithRequestID(req.Context(), id)))
+	})
+}
+
+// NewRequestID returns a random 128-bit request ID in hex.
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether id is short enough and made of safe
+// characters only.
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("-_.:+/=@", c) >= 0 {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// traceIDFromTraceparent returns the trace ID of a W3C traceparent header
+// value `version-traceid-parentid-flags`, or an empty string if it's invalid.
+func traceIDFromTraceparent(tp string) string {
+	parts := strings.Split(strings.TrimSpace(tp), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	return strings.ToLower(parts[1])
+}
+
+// RequestIDTransport is an http.RoundTripper which forwards the request ID
+// carried by the context of outbound requests.
+type RequestIDTransport struct {
+	// Base is the RoundTripper doing the request, http.DefaultTransport if nil.
+	Base http.RoundTripper
+	// Header is the header the ID is sent in, DefRequestIDHeader if empty.
+	Header string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	header := t.Header
+	if header == "" {
+		header = DefRequestIDHeader
+	}
+
+	id := RequestIDFromContext(req.Context())
+	if id == "" || req.Header.Get(header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set(header, id)
+	return base.RoundTrip(req)
+}
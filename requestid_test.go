@@ -0,0 +1,129 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID_PropagatesHeader(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = RequestIDFromContext(req.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "abc-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got != "abc-1" {
+		t.Fatalf("expected request id abc-1 in context, got %q", got)
+	}
+	if rec.Header().Get("X-Request-Id") != "abc-1" {
+		t.Fatalf("expected request id on response, got %q", rec.Header().Get("X-Request-Id"))
+	}
+}
+
+func TestRequestID_GeneratesAndUsesTraceparent(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = RequestIDFromContext(req.Context())
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(got) != 32 {
+		t.Fatalf("expected generated 32 char request id, got %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected trace id from traceparent, got %q", got)
+	}
+}
+
+func TestRequestID_ReplacesUnsafeHeader(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = RequestIDFromContext(req.Context())
+	}))
+
+	for _, id := range []string{"abc\nI10:04:05 forged] line", "a b", `"quoted"`, strings.Repeat("a", 129)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-Id", id)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if len(got) != 32 || got == id || rec.Header().Get("X-Request-Id") != got {
+			t.Fatalf("expected a generated request id instead of %q, got %q", id, got)
+		}
+	}
+
+	id := "Z2F0ZXdheQ==/7:" + strings.Repeat("a", 113)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", id)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got != id {
+		t.Fatalf("expected the safe request id kept, got %q", got)
+	}
+}
+
+func TestRequestIDWithOptions_CustomHeader(t *testing.T) {
+	var meta map[string]interface{}
+	h := RequestIDWithOptions(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		meta = EnrichHTTPMeta(400, req, nil, 1)
+	}), RequestIDOptions{Header: "X-Correlation-Id", Generate: func() string { return "gen-1" }})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("X-Correlation-Id") != "gen-1" {
+		t.Fatalf("expected generated id on custom header, got %q", rec.Header().Get("X-Correlation-Id"))
+	}
+	if meta["X-Request-Id"] != "gen-1" {
+		t.Fatalf("expected EnrichHTTPMeta to pick up id from context, got %v", meta["X-Request-Id"])
+	}
+}
+
+func TestRequestIDTransport_ForwardsID(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("X-Request-Id")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &RequestIDTransport{}}
+	req, _ := http.NewRequestWithContext(ContextWithRequestID(context.Background(), "out-7"), http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got != "out-7" {
+		t.Fatalf("expected forwarded request id out-7, got %q", got)
+	}
+	if req.Header.Get("X-Request-Id") != "" {
+		t.Fatalf("original request must not be modified")
+	}
+}
+
+func TestInfoContext_WritesFields(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("ctx", "ctx")
+
+	ctx := ContextWithRequestID(context.Background(), "req-9")
+	ctx = ContextWithFields(ctx, Fields{"user": "bob"})
+	InfoContext(ctx, "hello %d", 1)
+
+	out := readLogFile(t, dir, "ctx.info")
+	if !strings.Contains(out, "hello 1 request_id=req-9 user=bob") {
+		t.Fatalf("expected fields after message, got:\n%s", out)
+	}
+}
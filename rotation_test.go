@@ -3,8 +3,10 @@ package log
 import (
 	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestSizeRotation writes enough data with a small size threshold and expects
@@ -40,3 +42,101 @@ func TestSizeRotation(t *testing.T) {
 		t.Fatalf("expected multiple log files created by rotation, got %d", count)
 	}
 }
+
+// countLogFiles returns the number of .log files in dir.
+func countLogFiles(t *testing.T, dir string) int {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	return len(matches)
+}
+
+// TestReopen moves the logfile away as logrotate does and expects the logs to
+// go to a new file with the same name.
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetLogThrough(false)
+	SetFilenamePrefix("rot", "rot")
+
+	Info("before")
+	target, err := os.Readlink(filepath.Join(dir, "rot.info"))
+	if err != nil {
+		t.Fatalf("readlink failed: %v", err)
+	}
+	if err := os.Rename(filepath.Join(dir, target), filepath.Join(dir, target+".1")); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if err := Reopen(); err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	Info("after")
+
+	if got := readLogFile(t, dir, "rot.info"); strings.Contains(got, "before") || !strings.Contains(got, "after") {
+		t.Fatalf("expected only the log after reopen, got %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, target+".1")); !strings.Contains(string(got), "before") {
+		t.Fatalf("expected the log before reopen in the moved file, got %q", got)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "rot.warn")); !os.IsNotExist(err) {
+		t.Fatalf("expected no warn logfile opened by reopen, got %v", err)
+	}
+}
+
+// TestRotate expects a new logfile for every level having an open logfile.
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetLogThrough(false)
+	SetFilenamePrefix("rot", "rot")
+
+	Info("before")
+	Error("before")
+	if err := Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if n := countLogFiles(t, dir); n != 4 {
+		t.Fatalf("expected 4 logfiles, got %d", n)
+	}
+	Info("after")
+
+	if got := readLogFile(t, dir, "rot.info"); strings.Contains(got, "before") || !strings.Contains(got, "after") {
+		t.Fatalf("expected only the log after rotation, got %q", got)
+	}
+}
+
+// TestFileCheck_OSFile deletes the logfile and expects it to be recreated by
+// the check of the file.
+func TestFileCheck_OSFile(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetLogThrough(false)
+	SetFilenamePrefix("rot", "rot")
+	SetFileCheckInterval(time.Nanosecond)
+
+	Info("before")
+	target, err := os.Readlink(filepath.Join(dir, "rot.info"))
+	if err != nil {
+		t.Fatalf("readlink failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, target)); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	Info("after")
+
+	if got := readLogFile(t, dir, "rot.info"); strings.Contains(got, "before") || !strings.Contains(got, "after") {
+		t.Fatalf("expected the log in the recreated file, got %q", got)
+	}
+}
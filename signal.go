@@ -0,0 +1,70 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos linux netbsd openbsd solaris
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleSignals starts handling the signals controlling the logs:
+//
+//	SIGHUP:  Reopen, e.g. after logrotate moved the logfiles away
+//	SIGUSR1: turn trace and debug logs on, or off if either is on
+//	SIGUSR2: Rotate
+//
+// It returns a function to stop handling them.
+func HandleSignals() (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-c:
+				handleSignal(sig)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+func handleSignal(sig os.Signal) {
+	switch sig {
+	case syscall.SIGHUP:
+		if err := Reopen(); err != nil {
+			logDepth(logLevelError, 0, nil, "Failed to reopen logfiles on %v: %v", []interface{}{sig, err})
+			return
+		}
+		logDepth(logLevelInfo, 0, nil, "Reopened logfiles on %v", []interface{}{sig})
+	case syscall.SIGUSR1:
+		var on bool
+		updateConf(func(conf *config) {
+			on = !conf.logTrace() && !conf.logDebug()
+			conf.setFlags(flagLogTrace|flagLogDebug, on)
+		})
+		logDepth(logLevelInfo, 0, nil, "Trace and debug logs turned %s on %v", []interface{}{onOff(on), sig})
+	case syscall.SIGUSR2:
+		if err := Rotate(); err != nil {
+			logDepth(logLevelError, 0, nil, "Failed to rotate logfiles on %v: %v", []interface{}{sig, err})
+			return
+		}
+		logDepth(logLevelInfo, 0, nil, "Rotated logfiles on %v", []interface{}{sig})
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,10 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!illumos,!linux,!netbsd,!openbsd,!solaris
+
+package log
+
+// HandleSignals does nothing on the platforms without SIGHUP, SIGUSR1 and
+// SIGUSR2, e.g. Windows or Plan 9. It returns a function doing nothing.
+func HandleSignals() (stop func()) {
+	return func() {}
+}
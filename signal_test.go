@@ -0,0 +1,50 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos linux netbsd openbsd solaris
+
+package log
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestHandleSignals(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetLogThrough(false)
+	SetFilenamePrefix("sig", "sig")
+	entries := make(entrySink, 16)
+	defer AddSink(entries)()
+
+	stop := HandleSignals()
+	defer stop()
+
+	Error("before")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	waitEntry(t, entries, "Rotated logfiles")
+	// the rotated error file and the info file of the rotation log
+	if n := countLogFiles(t, dir); n != 3 {
+		t.Fatalf("expected 3 logfiles after the rotation, got %d", n)
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	waitEntry(t, entries, "turned on")
+	if !loadConf().logDebug() || !loadConf().logTrace() {
+		t.Fatal("expected trace and debug logs turned on")
+	}
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	waitEntry(t, entries, "turned off")
+	if loadConf().logDebug() || loadConf().logTrace() {
+		t.Fatal("expected trace and debug logs turned off")
+	}
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Entry is a log as passed to the sinks.
+type Entry struct {
+	Level   Level
+	Time    time.Time
+	Message string
+	Fields  Fields
+	Caller  Caller
+}
+
+// Caller is the location of the code which wrote an entry.
+type Caller struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// String returns the caller as `file.go:12`, or an empty string if unknown.
+func (c Caller) String() string {
+	if c.File == "" {
+		return ""
+	}
+	return path.Base(c.File) + ":" + strconv.Itoa(c.Line)
+}
+
+// Sink receives the entries written in addition to the logfiles. Log is called
+// synchronously by the goroutine writing the entry, possibly from several
+// goroutines at once.
+type Sink interface {
+	Log(e Entry)
+}
+
+var gSinks struct {
+	lock sync.RWMutex
+	list []Sink
+}
+
+// AddSink adds a sink receiving every entry written from now on. The
+// returned function removes it.
+func AddSink(s Sink) (remove func()) {
+	gSinks.lock.Lock()
+	gSinks.list = append(gSinks.list[:len(gSinks.list):len(gSinks.list)], s)
+	gSinks.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			gSinks.lock.Lock()
+			defer gSinks.lock.Unlock()
+			for i, v := range gSinks.list {
+				if v == s {
+					list := make([]Sink, 0, len(gSinks.list)-1)
+					gSinks.list = append(append(list, gSinks.list[:i]...), gSinks.list[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+func loadSinks() []Sink {
+	gSinks.lock.RLock()
+	list := gSinks.list
+	gSinks.lock.RUnlock()
+	return list
+}
+
+// logToSinks passes the entry to the sinks, skip is the number of stack
+// frames above logToSinks of the caller.
+func logToSinks(sinks []Sink, logLevel, skip int, t time.Time, fields Fields, format string, args []interface{}) {
+	e := newEntry(logLevel, skip+2, t, fields, format, args)
+	for _, s := range sinks {
+		s.Log(e)
+	}
+}
+
+// newEntry returns an entry, the caller is taken from runtime.Caller(skip).
+func newEntry(logLevel, skip int, t time.Time, fields Fields, format string, args []interface{}) Entry {
+	e := Entry{
+		Level:   Level(logLevel),
+		Time:    t,
+		Message: fmt.Sprintf(format, args...),
+		Fields:  fields,
+	}
+	if pc, file, line, ok := runtime.Caller(skip); ok {
+		e.Caller = Caller{File: file, Line: line}
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			e.Caller.Function = fn.Name()
+		}
+	}
+	return e
+}
@@ -0,0 +1,333 @@
+// Package sqllog wraps database/sql drivers so the queries they run are
+// written with query level of the go-log package:
+//
+//	sql.Register("postgres-log", sqllog.WrapDriver(&pq.Driver{}))
+//	db, err := sql.Open("postgres-log", dsn)
+//
+// or, for drivers providing a driver.Connector:
+//
+//	db := sql.OpenDB(sqllog.WrapConnector(connector))
+//
+// The fields carried by the context passed to the *Context methods of
+// database/sql, e.g. the request ID, are written with every query.
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	log "github.com/dainiauskas/go-log"
+)
+
+// Options configures the wrappers.
+type Options struct {
+	// OmitArgs skips logging the bound arguments.
+	OmitArgs bool
+	// Redact, if set, returns the value logged for a bound argument of query.
+	Redact func(query string, arg driver.NamedValue) interface{}
+}
+
+// WrapDriver returns a driver logging the calls of d.
+func WrapDriver(d driver.Driver) driver.Driver {
+	return WrapDriverWithOptions(d, Options{})
+}
+
+// WrapDriverWithOptions works like WrapDriver with the given options.
+func WrapDriverWithOptions(d driver.Driver, opts Options) driver.Driver {
+	wd := &wrappedDriver{Driver: d, opts: &opts}
+	if _, ok := d.(driver.DriverContext); ok {
+		return &wrappedDriverContext{wd}
+	}
+	return wd
+}
+
+// WrapConnector returns a connector logging the calls of the connections
+// returned by c.
+func WrapConnector(c driver.Connector) driver.Connector {
+	return WrapConnectorWithOptions(c, Options{})
+}
+
+// WrapConnectorWithOptions works like WrapConnector with the given options.
+func WrapConnectorWithOptions(c driver.Connector, opts Options) driver.Connector {
+	return &connector{Connector: c, opts: &opts}
+}
+
+type wrappedDriver struct {
+	driver.Driver
+	opts *Options
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, opts: d.opts}, nil
+}
+
+type wrappedDriverContext struct {
+	*wrappedDriver
+}
+
+func (d *wrappedDriverContext) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.Driver.(driver.DriverContext).OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{Connector: c, driver: d, opts: d.opts}, nil
+}
+
+type connector struct {
+	driver.Connector
+	driver driver.Driver
+	opts   *Options
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, opts: c.opts}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	if c.driver != nil {
+		return c.driver
+	}
+	return &wrappedDriver{Driver: c.Connector.Driver(), opts: c.opts}
+}
+
+type conn struct {
+	driver.Conn
+	opts *Options
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var s driver.Stmt
+	var err error
+	if cpc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = cpc.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		s, err = c.Conn.Prepare(query)
+	}
+	c.opts.log(ctx, "Prepare", query, nil, start, -1, err)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c, query: query}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if cbt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = cbt.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		err = errors.New("sqllog: driver does not support non-default isolation level or read-only transactions")
+	} else if err = ctx.Err(); err == nil {
+		tx, err = c.Conn.Begin()
+	}
+	c.opts.log(ctx, "Begin", "", nil, start, -1, err)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedTx{Tx: tx, ctx: ctx, opts: c.opts}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		res, err = ec.ExecContext(ctx, query, args)
+	} else if e, ok := c.Conn.(driver.Execer); ok {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = e.Exec(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	c.opts.log(ctx, "Exec", query, args, start, rowsAffected(res), err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		rows, err = qc.QueryContext(ctx, query, args)
+	} else if q, ok := c.Conn.(driver.Queryer); ok {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	c.opts.log(ctx, "Query", query, args, start, -1, err)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	driver.Stmt
+	conn  *conn
+	query string
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if sec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = sec.ExecContext(ctx, args)
+	} else if err = ctx.Err(); err == nil {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.conn.opts.log(ctx, "Exec", s.query, args, start, rowsAffected(res), err)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if sqc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = sqc.QueryContext(ctx, args)
+	} else if err = ctx.Err(); err == nil {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	s.conn.opts.log(ctx, "Query", s.query, args, start, -1, err)
+	return rows, err
+}
+
+// CheckNamedValue hands over to the statement, then to the connection, as
+// database/sql does not ask the connection when the statement implements it.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+type wrappedTx struct {
+	driver.Tx
+	ctx  context.Context
+	opts *Options
+}
+
+func (tx *wrappedTx) Commit() error {
+	start := time.Now()
+	err := tx.Tx.Commit()
+	tx.opts.log(tx.ctx, "Commit", "", nil, start, -1, err)
+	return err
+}
+
+func (tx *wrappedTx) Rollback() error {
+	start := time.Now()
+	err := tx.Tx.Rollback()
+	tx.opts.log(tx.ctx, "Rollback", "", nil, start, -1, err)
+	return err
+}
+
+// log writes the call with query level, rows < 0 means unknown.
+func (o *Options) log(ctx context.Context, op, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	elapsed := time.Since(start)
+	if op == "Exec" || op == "Query" {
+		log.RecordQuery(query, elapsed)
+	}
+
+	fields := log.Fields{"duration": elapsed}
+	if len(args) > 0 && !o.OmitArgs {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			if o.Redact != nil {
+				values[i] = o.Redact(query, arg)
+			} else {
+				values[i] = arg.Value
+			}
+		}
+		fields["args"] = values
+	}
+	if rows >= 0 {
+		fields["rows"] = rows
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+
+	ctx = log.ContextWithFields(ctx, fields)
+	if query == "" {
+		log.QueryContext(ctx, "%s", op)
+	} else {
+		log.QueryContext(ctx, "%s=[%s]", op, query)
+	}
+}
+
+func rowsAffected(res driver.Result) int64 {
+	if res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqllog: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,173 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/dainiauskas/go-log"
+)
+
+// fake driver: every Exec affects 2 rows, queries return no rows and a query
+// containing "fail" returns an error.
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("fake failure")
+	}
+	return driver.RowsAffected(2), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return fakeRows{}, nil
+}
+
+type fakeStmt struct{ query string }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(2), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+var logDir string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "sqllog-")
+	if err != nil {
+		panic(err)
+	}
+	logDir = dir
+	if err := log.Init(dir, 1, false); err != nil {
+		panic(err)
+	}
+	log.SetFilenamePrefix("sql", "sql")
+	log.SetLogThrough(false)
+
+	sql.Register("sqllog-fake", WrapDriverWithOptions(fakeDriver{}, Options{
+		Redact: func(query string, arg driver.NamedValue) interface{} {
+			if arg.Ordinal == 2 {
+				return "***"
+			}
+			return arg.Value
+		},
+	}))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func queryLog(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(logDir, "sql.query"))
+	if err != nil {
+		t.Fatalf("failed to read query log: %v", err)
+	}
+	return string(data)
+}
+
+func TestWrapDriver_LogsExecWithArgsAndContext(t *testing.T) {
+	db, err := sql.Open("sqllog-fake", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := log.ContextWithRequestID(context.Background(), "req-sql")
+	if _, err := db.ExecContext(ctx, "INSERT INTO users VALUES (?, ?)", 7, "s3cret"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE fail"); err == nil {
+		t.Fatalf("expected Exec to fail")
+	}
+
+	out := queryLog(t)
+	for _, want := range []string{"Exec=[INSERT INTO users VALUES (?, ?)]", `args="[7 ***]"`, "rows=2", "request_id=req-sql", "Exec=[DELETE fail]", `error="fake failure"`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in query log, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Fatalf("expected redacted argument, got:\n%s", out)
+	}
+}
+
+func TestWrapDriver_LogsPrepareAndTx(t *testing.T) {
+	db, err := sql.Open("sqllog-fake", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	st, err := tx.Prepare("UPDATE accounts SET x = ?")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if _, err := st.Exec(1); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	st.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	out := queryLog(t)
+	for _, want := range []string{"] Begin", "Prepare=[UPDATE accounts SET x = ?]", "Exec=[UPDATE accounts SET x = ?]", "] Commit"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in query log, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWrapConnector_LogsQuery(t *testing.T) {
+	db := sql.OpenDB(WrapConnector(fakeConnector{}))
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM connector_table")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	rows.Close()
+
+	if out := queryLog(t); !strings.Contains(out, "Query=[SELECT id FROM connector_table]") {
+		t.Fatalf("expected query in log, got:\n%s", out)
+	}
+}
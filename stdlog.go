@@ -0,0 +1,105 @@
+package log
+
+import (
+	stdlog "log"
+	"runtime"
+	"strings"
+)
+
+// NewStdLogger returns a *log.Logger of the standard library writing with the
+// given level, e.g. for http.Server.ErrorLog. The file and line number are
+// those of the code calling the *log.Logger.
+func NewStdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{level: level}, "", 0)
+}
+
+// RedirectStdLog redirects the output of the standard library log package to
+// the given level. The returned function restores the previous output, flags
+// and prefix.
+func RedirectStdLog(level Level) (restore func()) {
+	flags, prefix, w := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdLogWriter{level: level})
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(w)
+	}
+}
+
+// SetStdLogTags sets whether a leading level tag, like `[WARN]` or `ERROR:`,
+// of lines written through NewStdLogger and RedirectStdLog picks the level.
+// By default, the level passed to them is used.
+func SetStdLogTags(on bool) {
+	setFlags(flagStdLogTags, on)
+}
+
+// stdLogTags are the tags recognised by SetStdLogTags in addition to the
+// level names.
+var stdLogTags = map[string]int{
+	"warning":  logLevelWarn,
+	"err":      logLevelError,
+	"fatal":    logLevelAbort,
+	"critical": logLevelPanic,
+}
+
+type stdLogWriter struct {
+	level Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	logLevel := int(w.level)
+	if loadConf().stdLogTags() {
+		logLevel, msg = parseStdLogTag(logLevel, msg)
+	}
+	if Level(logLevel).valid() && loadConf().levelEnabled(logLevel) {
+		logDepth(logLevel, stdLogCallerDepth(), nil, "%s", []interface{}{msg})
+	}
+	return len(p), nil
+}
+
+// stdLogCallerDepth returns the number of frames of the log package between
+// Write and the code calling it.
+func stdLogCallerDepth() int {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for depth := 0; ; depth++ {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "log.") || !more {
+			return depth
+		}
+	}
+}
+
+// parseStdLogTag returns the level of a leading `[TAG]` or `TAG:` of msg and
+// msg without it. It returns logLevel and msg if there is no known tag.
+func parseStdLogTag(logLevel int, msg string) (int, string) {
+	var tag, rest string
+	if strings.HasPrefix(msg, "[") {
+		i := strings.IndexByte(msg, ']')
+		if i < 0 {
+			return logLevel, msg
+		}
+		tag, rest = msg[1:i], msg[i+1:]
+	} else {
+		i := strings.IndexByte(msg, ':')
+		if i < 0 || strings.ContainsAny(msg[:i], " \t") {
+			return logLevel, msg
+		}
+		tag, rest = msg[:i], msg[i+1:]
+	}
+
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if l, ok := stdLogTags[tag]; ok {
+		return l, strings.TrimLeft(rest, " ")
+	}
+	for i, d := range loadConf().levels {
+		if d.name == tag {
+			return i, strings.TrimLeft(rest, " ")
+		}
+	}
+	return logLevel, msg
+}
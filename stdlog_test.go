@@ -0,0 +1,62 @@
+package log
+
+import (
+	stdlog "log"
+	"strings"
+	"testing"
+)
+
+func TestNewStdLogger_WritesWithLevelAndCaller(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("std", "std")
+
+	l := NewStdLogger(LevelWarn)
+	l.Printf("disk at %d%%", 91)
+	l.Println("second line")
+
+	out := readLogFile(t, dir, "std.warn")
+	if !strings.Contains(out, "stdlog_test.go:") || !strings.Contains(out, "] disk at 91%\n") || !strings.Contains(out, "] second line\n") {
+		t.Fatalf("unexpected warn log:\n%s", out)
+	}
+}
+
+func TestRedirectStdLog_ParsesTags(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("std", "std")
+	SetLogThrough(false)
+	SetStdLogTags(true)
+
+	restore := RedirectStdLog(LevelInfo)
+	stdlog.Print("[WARN] cache is cold")
+	stdlog.Print("ERROR: connection refused")
+	stdlog.Print("plain message: with colon")
+	restore()
+
+	var buf strings.Builder
+	orig := stdlog.Writer()
+	stdlog.SetOutput(&buf)
+	stdlog.Print("not redirected")
+	stdlog.SetOutput(orig)
+	if !strings.Contains(buf.String(), "not redirected") {
+		t.Fatalf("expected output restored")
+	}
+
+	if out := readLogFile(t, dir, "std.warn"); !strings.Contains(out, "] cache is cold") || !strings.Contains(out, "stdlog_test.go:") {
+		t.Fatalf("unexpected warn log:\n%s", out)
+	}
+	if out := readLogFile(t, dir, "std.error"); !strings.Contains(out, "] connection refused") {
+		t.Fatalf("unexpected error log:\n%s", out)
+	}
+	out := readLogFile(t, dir, "std.info")
+	if !strings.Contains(out, "] plain message: with colon") || strings.Contains(out, "not redirected") {
+		t.Fatalf("unexpected info log:\n%s", out)
+	}
+}
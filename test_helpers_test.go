@@ -1,38 +1,74 @@
 package log
 
-import "time"
-
-// gMaxFileSizeBytes controls rotation by size when > 0. Default 0 (disabled).
-// The variable itself is declared in logger.go; tests may override it via
-// SetMaxFileSizeBytes.
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 // SetMaxFileSizeBytes configures the file size threshold (in bytes) that triggers
 // rotation when the current logfile grows beyond this value. A value of 0
 // disables size-based rotation.
 func SetMaxFileSizeBytes(n int64) {
-	gMaxFileSizeBytes = n
+	updateConf(func(conf *config) { conf.maxFileSize = n })
 }
 
 // ResetForTests closes any open logger files and resets internal state. This is
 // intended for use by tests to ensure a clean environment between test cases.
 func ResetForTests() {
-	for i := range gLoggers {
-		gLoggers[i].lock.Lock()
-		if gLoggers[i].file != nil {
-			_ = gLoggers[i].file.Close()
-			gLoggers[i].file = nil
-		}
-		gLoggers[i].day = 0
-		gLoggers[i].size = 0
-		gLoggers[i].purged = time.Time{}
-		gLoggers[i].lock.Unlock()
+	// the loggers are replaced by the ones of the default config
+	closeFiles()
+	gFlags.lock.Lock()
+	gFlags.values = map[string]string{}
+	gFlags.lock.Unlock()
+	gConfigSinks.lock.Lock()
+	for name, remove := range gConfigSinks.removes {
+		remove()
+		delete(gConfigSinks.removes, name)
+	}
+	gConfigSinks.lock.Unlock()
+	gAdmin.lock.Lock()
+	for name, r := range gAdmin.restores {
+		r.stop()
+		delete(gAdmin.restores, name)
 	}
+	gAdmin.lock.Unlock()
 	// Reset config to defaults used on package init
-	gConf = config{
-		logPath:  "./log/",
-		logflags: flagLogFilenameLineNum | flagLogThrough,
-		maxdays:  30,
-		enabled:  true,
+	gConfValue.Store(defaultConfig())
+}
+
+// readLogFile returns the contents of the logfile the given symlink in dir
+// points to, e.g. readLogFile(t, dir, "prefix.error").
+func readLogFile(t *testing.T, dir, symlink string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, symlink))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", symlink, err)
+	}
+	return string(data)
+}
+
+// entrySink passes the entries to a channel, so a test can wait for the logs
+// written by another goroutine.
+type entrySink chan Entry
+
+func (s entrySink) Log(e Entry) { s <- e }
+
+// waitEntry returns the next entry of s whose message contains snippet,
+// skipping the others.
+func waitEntry(t *testing.T, s entrySink, snippet string) Entry {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-s:
+			if strings.Contains(e.Message, snippet) {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no log containing %q", snippet)
+		}
 	}
-	isSymlink = map[string]bool{}
 }
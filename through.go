@@ -0,0 +1,113 @@
+package log
+
+import "fmt"
+
+// logLevelAll is the level of the logger of the combined logfile.
+const logLevelAll = -1
+
+// allFileName is the name of the combined logfile, used like a level name.
+const allFileName = "all"
+
+// gThroughLevels are the levels whose logfiles, by default, the logs of the
+// more severe levels are written through to. The other levels are side
+// channels, e.g. query or debug, which only get their own logs.
+var gThroughLevels = []int{logLevelInfo, logLevelWarn, logLevelError, logLevelPanic, logLevelAbort}
+
+// defaultThrough returns the levels the built-in level logLevel is written
+// through to by default: those of gThroughLevels with a lower rank. The
+// registered levels are written to their own logfile only.
+func (conf *config) defaultThrough(logLevel int) []int {
+	if logLevel == logLevelTrace || logLevel >= logLevelMax {
+		return nil
+	}
+	var into []int
+	for _, i := range gThroughLevels {
+		if conf.levels[i].rank < conf.levels[logLevel].rank {
+			into = append(into, i)
+		}
+	}
+	return into
+}
+
+// SetThrough sets the levels whose logfiles the logs of level are written to
+// as well, while log-through is turned on with SetLogThrough, e.g. audit logs
+// written to the info logfile too:
+//
+//	log.SetThrough(audit, log.LevelInfo)
+//
+// By default, info, warn, error, panic and abort logs, and update logs, are
+// written through to the logfiles of the levels among info, warn, error,
+// panic and abort with a lower Rank; query, debug and the registered levels
+// to their own logfile only. Without levels, the logs of level are written to
+// its own logfile only. The trace logfile gets the logs of every level while
+// trace logs are turned on, so it cannot be set.
+func SetThrough(level Level, into ...Level) error {
+	var err error
+	updateConf(func(conf *config) {
+		if !conf.validLevel(int(level)) || level == LevelTrace {
+			err = fmt.Errorf("invalid log level %d to write through", level)
+			return
+		}
+
+		var levels []int
+		seen := map[Level]bool{level: true}
+		for _, l := range into {
+			if !conf.validLevel(int(l)) || l == LevelTrace {
+				err = fmt.Errorf("invalid log level %d to write %s through to", l, conf.levels[level].name)
+				return
+			}
+			if !seen[l] {
+				seen[l] = true
+				levels = append(levels, int(l))
+			}
+		}
+
+		// a new slice, the previous one is shared with the stored config
+		through := append([][]int(nil), conf.through...)
+		through[level] = levels
+		conf.through = through
+	})
+	return err
+}
+
+// GetThrough returns the levels whose logfiles the logs of level are written
+// to as well, see SetThrough.
+func GetThrough(level Level) []Level {
+	conf := loadConf()
+	if !conf.validLevel(int(level)) {
+		return nil
+	}
+	into := make([]Level, len(conf.through[level]))
+	for i, l := range conf.through[level] {
+		into[i] = Level(l)
+	}
+	return into
+}
+
+// ResetThrough sets the levels every level is written through to back to the
+// defaults described by SetThrough.
+func ResetThrough() {
+	updateConf(func(conf *config) {
+		through := make([][]int, len(conf.levels))
+		for i := range through {
+			through[i] = conf.defaultThrough(i)
+		}
+		conf.through = through
+	})
+}
+
+// SetAllFile sets whether to write every log to the combined "all" logfile
+// as well, exactly once whatever its level and the log-through settings. It
+// is rotated and purged like the logfiles of the levels.
+// By default, the combined logfile is not written.
+func SetAllFile(on bool) {
+	setFlags(flagLogAll, on)
+	if !on {
+		loadConf().all.close()
+	}
+}
+
+// GetAllFile reports whether the combined logfile is written, see SetAllFile.
+func GetAllFile() bool {
+	return loadConf().logAll()
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultThrough(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+	SetLogDebug(true)
+
+	Debug("debug line")
+	Query("query line")
+	Update("update line")
+	Error("error line")
+
+	for file, want := range map[string][]string{
+		"x.debug":  {"debug line"},
+		"x.query":  {"query line"},
+		"x.update": {"update line"},
+		"x.info":   {"update line", "error line"},
+		"x.warn":   {"error line"},
+		"x.error":  {"error line"},
+	} {
+		data := readLogFile(t, dir, file)
+		if n := strings.Count(data, "\n"); n != len(want) {
+			t.Fatalf("expected %d lines in %s, got:\n%s", len(want), file, data)
+		}
+		for _, line := range want {
+			if !strings.Contains(data, line) {
+				t.Fatalf("expected %q in %s, got:\n%s", line, file, data)
+			}
+		}
+	}
+	for _, file := range []string{"x.trace", "x.panic", "x.abort", "x.all"} {
+		if _, err := os.Lstat(filepath.Join(dir, file)); !os.IsNotExist(err) {
+			t.Fatalf("expected no %s logfile, got %v", file, err)
+		}
+	}
+
+	if got := GetThrough(LevelAbort); !reflect.DeepEqual(got, []Level{LevelInfo, LevelWarn, LevelError, LevelPanic}) {
+		t.Fatalf("unexpected abort levels %v", got)
+	}
+}
+
+func TestSetThrough(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+
+	audit, err := RegisterLevel("audit", 'S', 65)
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+	if err := SetThrough(audit, LevelInfo, LevelInfo, audit); err != nil {
+		t.Fatalf("SetThrough failed: %v", err)
+	}
+	if err := SetThrough(LevelError); err != nil {
+		t.Fatalf("SetThrough failed: %v", err)
+	}
+	if got := GetThrough(audit); !reflect.DeepEqual(got, []Level{LevelInfo}) {
+		t.Fatalf("unexpected audit levels %v", got)
+	}
+
+	Log(audit, "audit line")
+	Error("error line")
+
+	if data := readLogFile(t, dir, "x.info"); strings.Count(data, "\n") != 1 || !strings.Contains(data, "audit line") {
+		t.Fatalf("unexpected info logfile:\n%s", data)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "x.warn")); !os.IsNotExist(err) {
+		t.Fatalf("expected no warn logfile, got %v", err)
+	}
+
+	for _, c := range [][]Level{{LevelTrace}, {LevelInfo, LevelTrace}, {LevelInfo, Level(100)}, {Level(100)}} {
+		if err := SetThrough(c[0], c[1:]...); err == nil {
+			t.Fatalf("expected an error setting %v", c)
+		}
+	}
+
+	ResetThrough()
+	if got := GetThrough(LevelError); !reflect.DeepEqual(got, []Level{LevelInfo, LevelWarn}) {
+		t.Fatalf("unexpected error levels %v", got)
+	}
+	if got := GetThrough(audit); len(got) != 0 {
+		t.Fatalf("unexpected audit levels %v", got)
+	}
+}
+
+func TestAllFile(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, true); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+
+	SetAllFile(true)
+	if !GetAllFile() {
+		t.Fatalf("expected the combined logfile turned on")
+	}
+	Trace("trace line")
+	Info("info line")
+	Abort("abort line")
+	SetAllFile(false)
+	Info("not combined")
+
+	data := readLogFile(t, dir, "x.all")
+	for _, line := range []string{"trace line", "info line", "abort line"} {
+		if strings.Count(data, line) != 1 {
+			t.Fatalf("expected %q once in the combined logfile, got:\n%s", line, data)
+		}
+	}
+	if strings.Contains(data, "not combined") {
+		t.Fatalf("unexpected log in the combined logfile:\n%s", data)
+	}
+	if data := readLogFile(t, dir, "x.trace"); strings.Count(data, "\n") != 4 {
+		t.Fatalf("expected every log in the trace logfile, got:\n%s", data)
+	}
+
+	if _, err := RegisterLevel("ALL", 'L', 1); err == nil {
+		t.Fatalf("expected the combined logfile name reserved")
+	}
+}
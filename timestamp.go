@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeOptions configures the timestamp starting the FormatText lines, by
+// default the local time of day, e.g. 15:04:05.
+type TimeOptions struct {
+	// Date writes the date before the time, e.g. 2006-01-02 15:04:05.
+	Date bool
+	// Precision is the number of digits of the fraction of a second: 0, 3
+	// for milliseconds, 6 for microseconds or 9 for nanoseconds.
+	Precision int
+	// RFC3339 writes the date, a T, the time and the zone offset, e.g.
+	// 2006-01-02T15:04:05.000+03:00 or 2006-01-02T15:04:05.000Z in UTC.
+	RFC3339 bool
+	// Location is the time zone of the timestamps, of every format, e.g.
+	// time.UTC or one returned by time.LoadLocation. The local time zone is
+	// used if nil.
+	Location *time.Location
+}
+
+// DefaultTimeOptions returns the options used by default: the local time of
+// day, without the date nor the fraction of a second.
+func DefaultTimeOptions() TimeOptions {
+	return TimeOptions{}
+}
+
+// SetTimeOptions sets the timestamp of the FormatText lines, e.g. sortable
+// timestamps for logs merged from several files or hosts:
+//
+//	log.SetTimeOptions(log.TimeOptions{RFC3339: true, Precision: 6, Location: time.UTC})
+//
+// The other formats always write RFC 3339 timestamps with milliseconds, in
+// the time zone of Location.
+func SetTimeOptions(opts TimeOptions) error {
+	if err := checkTimePrecision(opts.Precision); err != nil {
+		return err
+	}
+	updateConf(func(conf *config) { conf.timeOpts = opts })
+	return nil
+}
+
+// GetTimeOptions returns the options set with SetTimeOptions.
+func GetTimeOptions() TimeOptions {
+	return loadConf().timeOpts
+}
+
+func checkTimePrecision(precision int) error {
+	switch precision {
+	case 0, 3, 6, 9:
+		return nil
+	}
+	return fmt.Errorf("invalid time precision %d: expected 0, 3, 6 or 9", precision)
+}
+
+// parseTimePrecision parses a precision given as s, ms, us or ns, or as a
+// number of digits.
+func parseTimePrecision(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "s", "0":
+		return 0, nil
+	case "ms", "3":
+		return 3, nil
+	case "us", "µs", "6":
+		return 6, nil
+	case "ns", "9":
+		return 9, nil
+	}
+	return 0, fmt.Errorf("invalid time precision %q: expected s, ms, us or ns", s)
+}
+
+// timestamp formats t as set by opts at buf.tmp[i] and returns the index
+// following it. It does not allocate.
+func (buf *buffer) timestamp(i int, t time.Time, opts *TimeOptions) int {
+	if opts.Location != nil {
+		t = t.In(opts.Location)
+	}
+
+	if opts.Date || opts.RFC3339 {
+		year, month, day := t.Date()
+		buf.nDigits(4, i, year)
+		buf.tmp[i+4] = '-'
+		buf.twoDigits(i+5, int(month))
+		buf.tmp[i+7] = '-'
+		buf.twoDigits(i+8, day)
+		buf.tmp[i+10] = ' '
+		if opts.RFC3339 {
+			buf.tmp[i+10] = 'T'
+		}
+		i += 11
+	}
+
+	h, m, s := t.Clock()
+	buf.twoDigits(i, h)
+	buf.tmp[i+2] = ':'
+	buf.twoDigits(i+3, m)
+	buf.tmp[i+5] = ':'
+	buf.twoDigits(i+6, s)
+	i += 8
+
+	if opts.Precision > 0 {
+		frac := t.Nanosecond()
+		for p := opts.Precision; p < 9; p++ {
+			frac /= 10
+		}
+		buf.tmp[i] = '.'
+		buf.nDigits(opts.Precision, i+1, frac)
+		i += 1 + opts.Precision
+	}
+
+	if opts.RFC3339 {
+		_, offset := t.Zone()
+		if offset == 0 {
+			buf.tmp[i] = 'Z'
+			return i + 1
+		}
+		buf.tmp[i] = '+'
+		if offset < 0 {
+			buf.tmp[i] = '-'
+			offset = -offset
+		}
+		buf.twoDigits(i+1, offset/3600)
+		buf.tmp[i+3] = ':'
+		buf.twoDigits(i+4, offset%3600/60)
+		i += 6
+	}
+	return i
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	vilnius := time.FixedZone("EEST", 3*3600)
+	at := time.Date(2022, 8, 1, 7, 4, 5, 123456789, time.UTC)
+	for _, c := range []struct {
+		opts TimeOptions
+		want string
+	}{
+		{TimeOptions{Location: time.UTC}, "07:04:05"},
+		{TimeOptions{Precision: 3, Location: time.UTC}, "07:04:05.123"},
+		{TimeOptions{Date: true, Precision: 6, Location: vilnius}, "2022-08-01 10:04:05.123456"},
+		{TimeOptions{RFC3339: true, Location: time.UTC}, "2022-08-01T07:04:05Z"},
+		{TimeOptions{RFC3339: true, Precision: 9, Location: vilnius}, "2022-08-01T10:04:05.123456789+03:00"},
+		{TimeOptions{RFC3339: true, Precision: 3, Location: time.FixedZone("", -(4*3600 + 30*60))}, "2022-08-01T02:34:05.123-04:30"},
+	} {
+		var buf buffer
+		n := buf.timestamp(0, at, &c.opts)
+		if got := string(buf.tmp[:n]); got != c.want {
+			t.Fatalf("timestamp(%+v) = %q, want %q", c.opts, got, c.want)
+		}
+	}
+
+	var buf buffer
+	opts := TimeOptions{RFC3339: true, Precision: 6, Location: vilnius}
+	if n := testing.AllocsPerRun(100, func() { buf.timestamp(1, at, &opts) }); n != 0 {
+		t.Fatalf("expected no allocations, got %v", n)
+	}
+}
+
+func TestSetTimeOptions(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+
+	if err := SetTimeOptions(TimeOptions{Precision: 2}); err == nil {
+		t.Fatalf("expected an error for an invalid precision")
+	}
+	opts := TimeOptions{RFC3339: true, Precision: 3, Location: time.UTC}
+	if err := SetTimeOptions(opts); err != nil {
+		t.Fatalf("SetTimeOptions failed: %v", err)
+	}
+	if GetTimeOptions() != opts {
+		t.Fatalf("unexpected options %+v", GetTimeOptions())
+	}
+	Info("stamped")
+
+	data := readLogFile(t, dir, "x.info")
+	if !regexp.MustCompile(`^I\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z timestamp_test\.go:\d+ .*\] stamped\n$`).MatchString(data) {
+		t.Fatalf("unexpected line %q", data)
+	}
+}
+
+func TestTimeOptionsStructured(t *testing.T) {
+	ResetForTests()
+	dir := t.TempDir()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	SetFilenamePrefix("x", "x")
+	SetLogFormat(FormatJSON)
+
+	if err := SetTimeOptions(TimeOptions{Location: time.FixedZone("", 5*3600)}); err != nil {
+		t.Fatalf("SetTimeOptions failed: %v", err)
+	}
+	Info("zoned")
+
+	data := readLogFile(t, dir, "x.info")
+	if !regexp.MustCompile(`^\{"time":"\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}\+05:00",`).MatchString(data) {
+		t.Fatalf("unexpected line %q", data)
+	}
+}
+
+func TestTimeConfigKeys(t *testing.T) {
+	ResetForTests()
+	defer ResetForTests()
+
+	var cfg FileConfig
+	for key, v := range map[string]string{"timeformat": "RFC3339", "timeprecision": "us", "timezone": "UTC"} {
+		if err := cfg.set(key, v); err != nil {
+			t.Fatalf("set %s failed: %v", key, err)
+		}
+	}
+	if err := cfg.Apply(); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if opts := GetTimeOptions(); !opts.RFC3339 || opts.Date || opts.Precision != 6 || opts.Location != time.UTC {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+
+	for key, v := range map[string]string{"timeformat": "unix", "timeprecision": "cs", "timezone": "Nowhere/Special"} {
+		if err := new(FileConfig).set(key, v); err == nil {
+			t.Fatalf("expected an error setting %s=%s", key, v)
+		}
+	}
+}
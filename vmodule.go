@@ -0,0 +1,150 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// verbosity bits of a vmodule rule
+const (
+	vTrace = 1 << iota
+	vDebug
+	vMatched // a rule matched, the other bits apply
+)
+
+// vmodule holds the rules set with SetVModule and the decision cached per
+// call site. It is never modified once stored in a config, but its cache.
+type vmodule struct {
+	spec  string
+	rules []vrule
+	cache sync.Map // pc uintptr -> uint8
+}
+
+type vrule struct {
+	pattern string
+	bits    uint8
+}
+
+// SetVModule sets the trace and debug verbosity per source file, overriding
+// SetLogTrace and SetLogDebug for the matching files. The spec is a
+// comma-separated list of pattern=verbosity, e.g.
+//
+//	payments/*=trace,gorm=off,handler_*=debug
+//
+// where trace writes trace and debug logs, debug writes debug logs only and
+// off writes neither. A pattern without a slash is matched against the
+// filename without the .go extension, a pattern with slashes against the
+// trailing path elements of the file, so payments/* matches every file of the
+// payments package. The first matching pattern is used. An empty spec clears
+// the rules.
+func SetVModule(spec string) error {
+	vm, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	updateConf(func(conf *config) { conf.vmodule = vm })
+	return nil
+}
+
+// GetVModule returns the spec set with SetVModule.
+func GetVModule() string {
+	if vm := loadConf().vmodule; vm != nil {
+		return vm.spec
+	}
+	return ""
+}
+
+func parseVModule(spec string) (*vmodule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	vm := &vmodule{spec: spec}
+	for _, item := range strings.Split(spec, ",") {
+		eq := strings.LastIndex(item, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid vmodule %q: expected pattern=verbosity", item)
+		}
+		pattern := strings.TrimSpace(item[:eq])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid vmodule pattern %q: %v", pattern, err)
+		}
+
+		var bits uint8
+		switch v := strings.ToLower(strings.TrimSpace(item[eq+1:])); v {
+		case "trace":
+			bits = vTrace | vDebug
+		case "debug":
+			bits = vDebug
+		case "off":
+		default:
+			return nil, fmt.Errorf("invalid vmodule verbosity %q: expected trace, debug or off", v)
+		}
+		vm.rules = append(vm.rules, vrule{pattern: strings.TrimSuffix(pattern, ".go"), bits: bits | vMatched})
+	}
+	return vm, nil
+}
+
+// verbose reports whether trace or debug logs, written by the caller depth
+// frames above the function calling verbose, are written down. The other
+// levels are always written down.
+func (conf *config) verbose(logLevel, depth int) bool {
+	if conf.vmodule == nil || (logLevel != logLevelTrace && logLevel != logLevelDebug) {
+		return conf.levelEnabled(logLevel)
+	}
+
+	var pcs [1]uintptr
+	if runtime.Callers(3+depth, pcs[:]) == 0 {
+		return conf.levelEnabled(logLevel)
+	}
+	bits := conf.vmodule.bitsAt(pcs[0])
+	if bits&vMatched == 0 {
+		return conf.levelEnabled(logLevel)
+	}
+	if logLevel == logLevelTrace {
+		return bits&vTrace != 0
+	}
+	return bits&vDebug != 0
+}
+
+// bitsAt returns the bits of the rule matching the file of pc, cached per pc.
+func (vm *vmodule) bitsAt(pc uintptr) uint8 {
+	if bits, ok := vm.cache.Load(pc); ok {
+		return bits.(uint8)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	bits := vm.match(frame.File)
+	vm.cache.Store(pc, bits)
+	return bits
+}
+
+// match returns the bits of the first rule matching file, zero if none does.
+func (vm *vmodule) match(file string) uint8 {
+	file = strings.TrimSuffix(file, ".go")
+	base := path.Base(file)
+	for _, r := range vm.rules {
+		if !strings.Contains(r.pattern, "/") {
+			if ok, _ := path.Match(r.pattern, base); ok {
+				return r.bits
+			}
+			continue
+		}
+		// match the trailing path elements
+		for p := file; ; {
+			if ok, _ := path.Match(r.pattern, p); ok {
+				return r.bits
+			}
+			slash := strings.Index(p, "/")
+			if slash < 0 {
+				break
+			}
+			p = p[slash+1:]
+		}
+	}
+	return 0
+}
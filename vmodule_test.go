@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseVModule(t *testing.T) {
+	vm, err := parseVModule(" payments/*=trace, gorm=off,handler_*.go=debug ")
+	if err != nil {
+		t.Fatalf("parseVModule failed: %v", err)
+	}
+	for file, want := range map[string]uint8{
+		"/src/app/payments/charge.go":   vMatched | vTrace | vDebug,
+		"/src/app/payments/x/refund.go": 0,
+		"/go/pkg/gorm.io/gorm/gorm.go":  vMatched,
+		"/src/app/api/handler_users.go": vMatched | vDebug,
+		"/src/app/api/users.go":         0,
+	} {
+		if got := vm.match(file); got != want {
+			t.Fatalf("match(%q) = %b, want %b", file, got, want)
+		}
+	}
+
+	if vm, err := parseVModule(""); vm != nil || err != nil {
+		t.Fatalf("expected no rules for an empty spec, got %v %v", vm, err)
+	}
+	for _, spec := range []string{"payments", "=trace", "payments=loud", "[=trace"} {
+		if _, err := parseVModule(spec); err == nil {
+			t.Fatalf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestSetVModule(t *testing.T) {
+	ResetForTests()
+	if err := Init(t.TempDir(), 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer ResetForTests()
+	obs := NewObserver()
+	defer AddSink(obs)()
+
+	Trace("muted")
+	if err := SetVModule("vmodule_test=trace"); err != nil {
+		t.Fatalf("SetVModule failed: %v", err)
+	}
+	if GetVModule() != "vmodule_test=trace" {
+		t.Fatalf("unexpected spec %q", GetVModule())
+	}
+	for i := 0; i < 2; i++ {
+		Trace("trace %d", i)
+		Debug("debug %d", i)
+	}
+	TraceContext(context.Background(), "trace context")
+	NewSinkLogger(obs).Debug("sink debug")
+	if n := obs.FilterLevel(LevelTrace).Len(); n != 3 {
+		t.Fatalf("expected 3 trace entries, got %d", n)
+	}
+	if n := obs.FilterLevel(LevelDebug).Len(); n != 3 {
+		t.Fatalf("expected 3 debug entries, got %d", n)
+	}
+
+	obs.TakeAll()
+	SetLogDebug(true)
+	if err := SetVModule("other=trace,vmodule_*=off"); err != nil {
+		t.Fatalf("SetVModule failed: %v", err)
+	}
+	Trace("muted")
+	Debug("muted")
+	Info("not affected")
+	if n := obs.Len(); n != 1 {
+		t.Fatalf("expected only the info entry, got %v", obs.All())
+	}
+}
+
+func BenchmarkTraceVModuleMuted(b *testing.B) {
+	ResetForTests()
+	defer ResetForTests()
+	if err := SetVModule("payments/*=trace"); err != nil {
+		b.Fatalf("SetVModule failed: %v", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Trace("muted %d", i)
+	}
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxWriterLine is the length after which a partial line written to a Writer
+// is logged without waiting for the end of line.
+const maxWriterLine = 64 * 1024
+
+// Writer returns an io.WriteCloser logging every line written to it with the
+// given level, e.g. to log the output of a child process:
+//
+//	cmd.Stdout = log.Writer(log.LevelInfo)
+//	cmd.Stderr = log.Writer(log.LevelError)
+//
+// Lines may span several writes, a partial last line is logged by Close.
+// It is safe to write from several goroutines.
+func Writer(level Level) io.WriteCloser {
+	return &levelWriter{level: level}
+}
+
+type levelWriter struct {
+	level Level
+	lock  sync.Mutex
+	buf   []byte
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) >= maxWriterLine {
+				w.flush()
+			}
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.flush()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close logs the partial last line, if any.
+func (w *levelWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.buf) > 0 {
+		w.flush()
+	}
+	return nil
+}
+
+// flush logs the buffered line, w.lock must be held.
+func (w *levelWriter) flush() {
+	line := bytes.TrimSuffix(w.buf, []byte{'\r'})
+	if w.level.valid() && loadConf().levelEnabled(int(w.level)) {
+		logDepth(int(w.level), 1, nil, "%s", []interface{}{line})
+	}
+	w.buf = w.buf[:0]
+}
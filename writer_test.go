@@ -0,0 +1,69 @@
+package log
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestWriter_SplitsLinesAcrossWrites(t *testing.T) {
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("wr", "wr")
+
+	w := Writer(LevelInfo)
+	w.Write([]byte("first li"))
+	w.Write([]byte("ne\nsecond line\r\nthi"))
+	w.Write([]byte("rd"))
+	if out := readLogFile(t, dir, "wr.info"); strings.Contains(out, "third") {
+		t.Fatalf("partial line must not be logged before Close:\n%s", out)
+	}
+	w.Close()
+
+	out := readLogFile(t, dir, "wr.info")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), out)
+	}
+	for i, want := range []string{"] first line", "] second line", "] third"} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Fatalf("line %d: expected suffix %q, got %q", i, want, lines[i])
+		}
+		if !strings.Contains(lines[i], "writer_test.go:") {
+			t.Fatalf("line %d: expected the caller of Write or Close, got %q", i, lines[i])
+		}
+	}
+}
+
+func TestWriter_ExecCmdOutput(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	ResetForTests()
+	if err := Init(dir, 1, false); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFilenamePrefix("wr", "wr")
+	SetLogThrough(false)
+
+	stdout, stderr := Writer(LevelInfo), Writer(LevelError)
+	cmd := exec.Command(sh, "-c", "echo out1; echo err1 >&2; printf out2")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	stdout.Close()
+	stderr.Close()
+
+	if out := readLogFile(t, dir, "wr.info"); !strings.Contains(out, "] out1\n") || !strings.Contains(out, "] out2\n") {
+		t.Fatalf("unexpected info log:\n%s", out)
+	}
+	if out := readLogFile(t, dir, "wr.error"); !strings.Contains(out, "] err1\n") {
+		t.Fatalf("unexpected error log:\n%s", out)
+	}
+}